@@ -0,0 +1,67 @@
+package bench
+
+import (
+	"sort"
+	"strings"
+)
+
+// SortedSlice is the baseline ordered store, entries kept sorted by key and found by binary search
+type SortedSlice struct {
+	keys   []string
+	values []string
+}
+
+// NewSortedSlice returns an empty SortedSlice, every Add shifts the entries past the new key
+func NewSortedSlice() *SortedSlice {
+	return &SortedSlice{}
+}
+
+func (s *SortedSlice) search(key string) (int, bool) {
+	i := sort.SearchStrings(s.keys, key)
+	return i, i < len(s.keys) && s.keys[i] == key
+}
+
+func (s *SortedSlice) Add(key string, value string) {
+	i, exists := s.search(key)
+	if exists {
+		s.values[i] = value
+		return
+	}
+	s.keys = append(s.keys, "")
+	copy(s.keys[i+1:], s.keys[i:])
+	s.keys[i] = key
+	s.values = append(s.values, "")
+	copy(s.values[i+1:], s.values[i:])
+	s.values[i] = value
+}
+
+func (s *SortedSlice) Get(key string) (string, bool) {
+	i, exists := s.search(key)
+	if !exists {
+		return "", false
+	}
+	return s.values[i], true
+}
+
+func (s *SortedSlice) Delete(key string) bool {
+	i, exists := s.search(key)
+	if !exists {
+		return false
+	}
+	s.keys = append(s.keys[:i], s.keys[i+1:]...)
+	s.values = append(s.values[:i], s.values[i+1:]...)
+	return true
+}
+
+// WalkPrefix calls fn for every key starting with prefix in key order until fn returns false
+func (s *SortedSlice) WalkPrefix(prefix string, fn func(key string, value string) bool) {
+	for i := sort.SearchStrings(s.keys, prefix); i < len(s.keys) && strings.HasPrefix(s.keys[i], prefix); i++ {
+		if !fn(s.keys[i], s.values[i]) {
+			return
+		}
+	}
+}
+
+func (s *SortedSlice) Len() int {
+	return len(s.keys)
+}
@@ -0,0 +1,337 @@
+package bench
+
+import (
+	"runtime"
+	"slices"
+	"testing"
+
+	"rtree/src"
+)
+
+const datasetSize = 10000
+
+var datasets = Datasets(datasetSize, 1)
+
+// store is the common surface of the benchmarked implementations
+type store interface {
+	Add(key string, value string)
+	Get(key string) (string, bool)
+	Delete(key string) bool
+	WalkPrefix(prefix string, fn func(key string, value string) bool)
+}
+
+type rtreeStore struct{ *src.RTree }
+
+func (s rtreeStore) Add(key string, value string) { s.RTree.Add(key, value) }
+
+type arenaStore struct{ *src.ArenaRTree }
+
+func (s arenaStore) Add(key string, value string) { s.ArenaRTree.Add(key, value) }
+
+// mapStore is the unordered baseline, a prefix scan has to look at every key
+type mapStore map[string]string
+
+func (m mapStore) Add(key string, value string) { m[key] = value }
+
+func (m mapStore) Get(key string) (string, bool) {
+	value, exists := m[key]
+	return value, exists
+}
+
+func (m mapStore) Delete(key string) bool {
+	_, exists := m[key]
+	delete(m, key)
+	return exists
+}
+
+func (m mapStore) WalkPrefix(prefix string, fn func(key string, value string) bool) {
+	for key, value := range m {
+		if len(key) >= len(prefix) && key[:len(prefix)] == prefix && !fn(key, value) {
+			return
+		}
+	}
+}
+
+var stores = []struct {
+	name string
+	new  func() store
+}{
+	{"RTree", func() store { return rtreeStore{src.NewRTree()} }},
+	{"ArenaRTree", func() store { return arenaStore{src.NewArenaRTree(0)} }},
+	{"Map", func() store { return mapStore{} }},
+	{"SortedSlice", func() store { return NewSortedSlice() }},
+}
+
+func fill(s store, keys []string) store {
+	for _, key := range keys {
+		s.Add(key, key)
+	}
+	return s
+}
+
+// forEach runs fn as a sub-benchmark for every dataset and store
+func forEach(b *testing.B, fn func(b *testing.B, keys []string, new func() store)) {
+	for _, dataset := range datasets {
+		for _, s := range stores {
+			b.Run(dataset.Name+"/"+s.name, func(b *testing.B) {
+				fn(b, dataset.Keys, s.new)
+			})
+		}
+	}
+}
+
+// BenchmarkAdd times filling an empty store with the whole dataset
+func BenchmarkAdd(b *testing.B) {
+	forEach(b, func(b *testing.B, keys []string, new func() store) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fill(new(), keys)
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+	})
+}
+
+func BenchmarkSearch(b *testing.B) {
+	forEach(b, func(b *testing.B, keys []string, new func() store) {
+		s := fill(new(), keys)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			s.Get(keys[i%len(keys)])
+		}
+	})
+}
+
+func BenchmarkSearchMissing(b *testing.B) {
+	forEach(b, func(b *testing.B, keys []string, new func() store) {
+		s := fill(new(), keys)
+		missing := make([]string, len(keys))
+		for i, key := range keys {
+			missing[i] = key + "~"
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			s.Get(missing[i%len(missing)])
+		}
+	})
+}
+
+// BenchmarkDelete times emptying a full store, the refill is not counted
+func BenchmarkDelete(b *testing.B) {
+	forEach(b, func(b *testing.B, keys []string, new func() store) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			s := fill(new(), keys)
+			b.StartTimer()
+			for _, key := range keys {
+				s.Delete(key)
+			}
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+	})
+}
+
+// BenchmarkAddBatch times filling an empty RTree with the whole dataset in one AddBatch against a loop of Add
+func BenchmarkAddBatch(b *testing.B) {
+	for _, dataset := range datasets {
+		entries := make([]src.Entry, len(dataset.Keys))
+		for i, key := range dataset.Keys {
+			entries[i] = src.Entry{Key: key, Value: key}
+		}
+		b.Run(dataset.Name+"/Loop", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				tree := src.NewRTree()
+				for _, entry := range entries {
+					tree.Add(entry.Key, entry.Value)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(entries)), "ns/key")
+		})
+		b.Run(dataset.Name+"/Batch", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				src.NewRTree().AddBatch(entries)
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(entries)), "ns/key")
+		})
+	}
+}
+
+// BenchmarkDeleteBatch times emptying a full RTree in one DeleteBatch against a loop of Delete, the refill is not counted.
+// Delete leaves the nodes it emptied for Compact while DeleteBatch removes them, LoopCompact pays for both
+func BenchmarkDeleteBatch(b *testing.B) {
+	for _, dataset := range datasets {
+		keys := dataset.Keys
+		loop := func(compact bool) func(b *testing.B) {
+			return func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					tree := src.NewRTree()
+					fill(rtreeStore{tree}, keys)
+					b.StartTimer()
+					for _, key := range keys {
+						tree.Delete(key)
+					}
+					if compact {
+						tree.Compact()
+					}
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+			}
+		}
+		b.Run(dataset.Name+"/Loop", loop(false))
+		b.Run(dataset.Name+"/LoopCompact", loop(true))
+		b.Run(dataset.Name+"/Batch", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				tree := src.NewRTree()
+				fill(rtreeStore{tree}, keys)
+				b.StartTimer()
+				tree.DeleteBatch(keys)
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+		})
+	}
+}
+
+// BenchmarkBuildFromSorted times building an RTree from the sorted dataset in one pass against a loop of Add in the same order
+func BenchmarkBuildFromSorted(b *testing.B) {
+	for _, dataset := range datasets {
+		keys := slices.Clone(dataset.Keys)
+		slices.Sort(keys)
+		entries := func(yield func(string, string) bool) {
+			for _, key := range keys {
+				if !yield(key, key) {
+					return
+				}
+			}
+		}
+		b.Run(dataset.Name+"/Loop", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				fill(rtreeStore{src.NewRTree()}, keys)
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+		})
+		b.Run(dataset.Name+"/Build", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := src.BuildFromSorted(entries, src.RejectDuplicates); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+		})
+	}
+}
+
+// BenchmarkPrefixScan walks the keys starting with the first half of a dataset key
+func BenchmarkPrefixScan(b *testing.B) {
+	forEach(b, func(b *testing.B, keys []string, new func() store) {
+		s := fill(new(), keys)
+		prefixes := make([]string, 0, 100)
+		for i := 0; i < len(keys); i += len(keys) / cap(prefixes) {
+			prefixes = append(prefixes, keys[i][:len(keys[i])/2])
+		}
+		visited := 0
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			s.WalkPrefix(prefixes[i%len(prefixes)], func(key string, value string) bool {
+				visited++
+				return true
+			})
+		}
+		b.ReportMetric(float64(visited)/float64(b.N), "keys/op")
+	})
+}
+
+// BenchmarkMemory reports the heap retained per key, keys and values are shared with the dataset
+func BenchmarkMemory(b *testing.B) {
+	forEach(b, func(b *testing.B, keys []string, new func() store) {
+		var before, after runtime.MemStats
+		retained := uint64(0)
+		for i := 0; i < b.N; i++ {
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			s := fill(new(), keys)
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			runtime.KeepAlive(s)
+			if after.HeapAlloc > before.HeapAlloc {
+				retained += after.HeapAlloc - before.HeapAlloc
+			}
+		}
+		b.ReportMetric(float64(retained)/float64(b.N*len(keys)), "bytes/key")
+	})
+}
+
+// BenchmarkGC times a full collection with a large tree live, the cost grows with the pointers to scan
+func BenchmarkGC(b *testing.B) {
+	keys := UUIDs(500000, 1)
+	for _, s := range stores[:2] {
+		b.Run(s.name, func(b *testing.B) {
+			live := fill(s.new(), keys)
+			runtime.GC()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+			}
+			b.StopTimer()
+			runtime.KeepAlive(live)
+		})
+	}
+}
+
+func TestDatasets(t *testing.T) {
+
+	again := Datasets(datasetSize, 1)
+	for i, dataset := range datasets {
+		if len(dataset.Keys) != datasetSize {
+			t.Errorf(`Dataset %s has %d keys`, dataset.Name, len(dataset.Keys))
+		}
+		seen := map[string]bool{}
+		for j, key := range dataset.Keys {
+			if seen[key] {
+				t.Errorf(`Dataset %s repeats key %s`, dataset.Name, key)
+			}
+			seen[key] = true
+			if again[i].Keys[j] != key {
+				t.Fatalf(`Dataset %s is not deterministic`, dataset.Name)
+			}
+		}
+	}
+}
+
+func TestStoresAgree(t *testing.T) {
+
+	for _, dataset := range datasets {
+		keys := dataset.Keys[:1000]
+		for _, s := range stores {
+			st := fill(s.new(), keys)
+			for _, key := range keys {
+				if value, exists := st.Get(key); !exists || value != key {
+					t.Errorf(`%s %s Not Found expected key %s`, dataset.Name, s.name, key)
+				}
+			}
+			count := 0
+			st.WalkPrefix("", func(key string, value string) bool {
+				count++
+				return true
+			})
+			if count != len(keys) {
+				t.Errorf(`%s %s WalkPrefix visited %d keys expected %d`, dataset.Name, s.name, count, len(keys))
+			}
+			for _, key := range keys {
+				if !st.Delete(key) {
+					t.Errorf(`%s %s Delete failed key %s`, dataset.Name, s.name, key)
+				}
+			}
+		}
+	}
+}
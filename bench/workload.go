@@ -0,0 +1,124 @@
+// Package bench generates the key sets used to benchmark the trees against each other
+// and against the map and sorted slice baselines
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Dataset is a named set of distinct keys
+type Dataset struct {
+	Name string
+	Keys []string
+}
+
+// Datasets returns every workload with n keys, the same seed always gives the same keys
+func Datasets(n int, seed int64) []Dataset {
+	return []Dataset{
+		{Name: "UUID", Keys: UUIDs(n, seed)},
+		{Name: "URLPath", Keys: URLPaths(n, seed)},
+		{Name: "Word", Keys: Words(n, seed)},
+		{Name: "Sequential", Keys: Sequential(n)},
+		{Name: "LongPrefix", Keys: LongPrefix(n, 200, seed)},
+	}
+}
+
+// UUIDs returns n version 4 UUIDs formatted like generateUUID in the tests
+func UUIDs(n int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	return distinct(n, func() string {
+		uuid := make([]byte, 16)
+		rng.Read(uuid)
+		uuid[6] = (uuid[6] & 0x0f) | 0x40
+		uuid[8] = (uuid[8] & 0x3f) | 0x80
+		return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+	})
+}
+
+var (
+	urlRoots     = []string{"api", "static", "admin", "docs", "blog", "shop"}
+	urlVersions  = []string{"v1", "v2", "v3"}
+	urlResources = []string{"users", "orders", "products", "invoices", "sessions", "comments", "tags", "images"}
+	urlActions   = []string{"", "edit", "history", "items", "settings", "export"}
+)
+
+// URLPaths returns n paths shaped like REST routes, few distinct first segments and numeric ids deeper down
+func URLPaths(n int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	return distinct(n, func() string {
+		path := fmt.Sprintf("/%s/%s/%s/%d",
+			urlRoots[rng.Intn(len(urlRoots))],
+			urlVersions[rng.Intn(len(urlVersions))],
+			urlResources[rng.Intn(len(urlResources))],
+			rng.Intn(100000))
+		if action := urlActions[rng.Intn(len(urlActions))]; action != "" {
+			path += "/" + action
+		}
+		return path
+	})
+}
+
+var (
+	wordStems = []string{
+		"act", "answer", "back", "bear", "break", "build", "call", "care", "carry", "change",
+		"clear", "close", "count", "cover", "cross", "cut", "deal", "draw", "dream", "drive",
+		"fall", "feel", "fill", "find", "fish", "form", "govern", "grow", "hand", "help",
+		"hold", "hope", "keep", "kind", "land", "lead", "learn", "light", "like", "line",
+		"list", "load", "look", "love", "mark", "move", "name", "need", "open", "order",
+		"paint", "pass", "place", "plant", "play", "point", "power", "press", "print", "read",
+		"rest", "ring", "round", "run", "sail", "school", "search", "sell", "send", "set",
+		"show", "sign", "sleep", "sound", "speak", "stand", "start", "stay", "step", "stop",
+		"store", "talk", "teach", "tell", "test", "think", "train", "turn", "view", "walk",
+		"want", "wash", "watch", "water", "work", "write", "yard", "year", "young", "zone",
+	}
+	wordPrefixes = []string{"", "", "", "re", "un", "over", "under", "out", "pre", "mis"}
+	wordSuffixes = []string{"", "", "s", "ed", "er", "ers", "ing", "ings", "able", "ful", "ness", "less", "ly"}
+)
+
+// Words returns n English like words built from common stems, affixes and compounds,
+// so keys share short prefixes and suffixes the way a dictionary does
+func Words(n int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	return distinct(n, func() string {
+		word := wordPrefixes[rng.Intn(len(wordPrefixes))] + wordStems[rng.Intn(len(wordStems))]
+		if rng.Intn(4) == 0 {
+			word += wordStems[rng.Intn(len(wordStems))]
+		}
+		return word + wordSuffixes[rng.Intn(len(wordSuffixes))]
+	})
+}
+
+// Sequential returns n zero padded ids in increasing order
+func Sequential(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("id-%012d", i)
+	}
+	return keys
+}
+
+// LongPrefix returns n keys sharing their first prefixLength bytes and differing in a random tail
+func LongPrefix(n int, prefixLength int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	prefix := strings.Repeat("tenant/acme/region/eu-west/", prefixLength/27+1)[:prefixLength]
+	return distinct(n, func() string {
+		return fmt.Sprintf("%s%08x", prefix, rng.Uint32())
+	})
+}
+
+// distinct calls next until it collected n different keys, keeping the generation order
+func distinct(n int, next func() string) []string {
+	keys := make([]string, 0, n)
+	seen := make(map[string]struct{}, n)
+	for len(keys) < n {
+		key := next()
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,254 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"rtree/src"
+)
+
+const (
+	paramMarker    = ':'
+	catchAllMarker = '*'
+)
+
+// Param is a path parameter captured while routing
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params holds the path parameters of a matched route in pattern order
+type Params []Param
+
+// Get returns the value of the parameter named name, or "" when missing
+func (p Params) Get(name string) string {
+	for _, param := range p {
+		if param.Key == name {
+			return param.Value
+		}
+	}
+	return ""
+}
+
+type paramsKey struct{}
+
+// ParamsFromContext returns the path parameters stored by the Router in the request context
+func ParamsFromContext(ctx context.Context) Params {
+	params, _ := ctx.Value(paramsKey{}).(Params)
+	return params
+}
+
+type route struct {
+	pattern string
+	names   []string
+	handler http.Handler
+}
+
+// Router dispatches requests through one radix tree per method,
+// patterns are made of static segments, ":name" segments capturing one path segment
+// and a final "*name" segment capturing the rest of the path,
+// static segments win over parameters which win over catch-alls
+type Router struct {
+	trees  map[string]*src.RTree
+	routes map[string]map[string]*route
+
+	// NotFound is called when no route matches the path, http.NotFound when nil
+	NotFound http.Handler
+	// MethodNotAllowed is called when the path matches only with other methods, a plain 405 when nil
+	MethodNotAllowed http.Handler
+}
+
+// New returns an empty Router
+func New() *Router {
+	return &Router{
+		trees:  map[string]*src.RTree{},
+		routes: map[string]map[string]*route{},
+	}
+}
+
+// Handle registers handler for method and pattern, it panics on invalid or duplicate patterns
+func (rt *Router) Handle(method string, pattern string, handler http.Handler) {
+	key, names, err := normalizePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	if handler == nil {
+		panic(fmt.Errorf("router: nil handler for %s %s", method, pattern))
+	}
+
+	tree, exists := rt.trees[method]
+	if !exists {
+		tree = src.NewRTree()
+		rt.trees[method] = tree
+		rt.routes[method] = map[string]*route{}
+	}
+	if existing, exists := rt.routes[method][key]; exists {
+		panic(fmt.Errorf("router: %s %s conflicts with %s", method, pattern, existing.pattern))
+	}
+
+	tree.Add(key, pattern)
+	rt.routes[method][key] = &route{
+		pattern: pattern,
+		names:   names,
+		handler: handler,
+	}
+}
+
+// HandleFunc registers handler for method and pattern
+func (rt *Router) HandleFunc(method string, pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	rt.Handle(method, pattern, http.HandlerFunc(handler))
+}
+
+// Lookup returns the handler and parameters routing method and path
+func (rt *Router) Lookup(method string, path string) (http.Handler, Params, bool) {
+	tree, exists := rt.trees[method]
+	if !exists {
+		return nil, nil, false
+	}
+	key, values, found := matchHandler(tree.Cursor(), path, 0, nil)
+	if !found {
+		return nil, nil, false
+	}
+
+	r := rt.routes[method][key]
+	params := make(Params, len(values))
+	for i, value := range values {
+		params[i] = Param{Key: r.names[i], Value: value}
+	}
+	return r.handler, params, true
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if handler, params, found := rt.Lookup(req.Method, req.URL.Path); found {
+		ctx := context.WithValue(req.Context(), paramsKey{}, params)
+		handler.ServeHTTP(w, req.WithContext(ctx))
+		return
+	}
+
+	if allowed := rt.allowedMethods(req.URL.Path); len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		if rt.MethodNotAllowed != nil {
+			rt.MethodNotAllowed.ServeHTTP(w, req)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if rt.NotFound != nil {
+		rt.NotFound.ServeHTTP(w, req)
+		return
+	}
+	http.NotFound(w, req)
+}
+
+func (rt *Router) allowedMethods(path string) []string {
+	allowed := []string{}
+	for method, tree := range rt.trees {
+		if _, _, found := matchHandler(tree.Cursor(), path, 0, nil); found {
+			allowed = append(allowed, method)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// normalizePattern replaces parameter names with their marker so that
+// "/users/:id" and "/users/:name" share the tree key "/users/:"
+func normalizePattern(pattern string) (string, []string, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return "", nil, fmt.Errorf("router: pattern %q must start with /", pattern)
+	}
+
+	segments := strings.Split(pattern[1:], "/")
+	names := []string{}
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		switch segment[0] {
+		case paramMarker, catchAllMarker:
+			name := segment[1:]
+			if name == "" {
+				return "", nil, fmt.Errorf("router: unnamed parameter in pattern %q", pattern)
+			}
+			if segment[0] == catchAllMarker && i != len(segments)-1 {
+				return "", nil, fmt.Errorf("router: catch-all must be the last segment in pattern %q", pattern)
+			}
+			names = append(names, name)
+			segments[i] = segment[:1]
+		}
+	}
+	return "/" + strings.Join(segments, "/"), names, nil
+}
+
+// matchHandler walks the compressed edges below cursor capturing parameter values,
+// it tries static edges first and backtracks to parameter and catch-all edges
+func matchHandler(cursor *src.Cursor, path string, previous byte, values []string) (string, []string, bool) {
+	if path == "" && cursor.IsEnd() {
+		return cursor.Key(), values, true
+	}
+
+	var static, params, catchAlls []*src.Cursor
+	for _, child := range cursor.Children() {
+		edge := child.Edge()
+		if edge == "" {
+			continue
+		}
+		switch {
+		case previous == '/' && edge[0] == paramMarker:
+			params = append(params, child)
+		case previous == '/' && edge[0] == catchAllMarker:
+			catchAlls = append(catchAlls, child)
+		default:
+			static = append(static, child)
+		}
+	}
+
+	for _, group := range [][]*src.Cursor{static, params, catchAlls} {
+		for _, child := range group {
+			edge := child.Edge()
+			rest, captured, ok := matchEdge(edge, path, previous, values)
+			if !ok {
+				continue
+			}
+			if key, matchedValues, found := matchHandler(child, rest, edge[len(edge)-1], captured); found {
+				return key, matchedValues, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+// matchEdge consumes the part of path matching edge and returns what is left
+func matchEdge(edge string, path string, previous byte, values []string) (string, []string, bool) {
+	captured := values[:len(values):len(values)]
+	for i := 0; i < len(edge); i++ {
+		switch {
+		case previous == '/' && edge[i] == paramMarker:
+			end := strings.IndexByte(path, '/')
+			if end < 0 {
+				end = len(path)
+			}
+			if end == 0 {
+				return "", nil, false
+			}
+			captured = append(captured, path[:end])
+			path = path[end:]
+		case previous == '/' && edge[i] == catchAllMarker:
+			captured = append(captured, path)
+			path = ""
+		default:
+			if path == "" || path[0] != edge[i] {
+				return "", nil, false
+			}
+			path = path[1:]
+		}
+		previous = edge[i]
+	}
+	return path, captured, true
+}
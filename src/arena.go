@@ -0,0 +1,322 @@
+package src
+
+import (
+	"sync"
+)
+
+// arenaNone marks a missing child or sibling, the root sits at index 0 and is never one
+const arenaNone = 0
+
+// arenaMaxSize bounds the byte buffer and the node count so every offset and index fits an uint32
+const arenaMaxSize = 1<<32 - 1
+
+// arenaNode holds no pointers, the edge label and the value are ranges of the shared byte buffer
+// and children form a list sorted by first edge byte, linked by index
+type arenaNode struct {
+	label    uint32
+	labelLen uint32
+	value    uint32
+	valueLen uint32
+	child    uint32
+	next     uint32
+	isEnd    bool
+}
+
+// ArenaRTree is a radix tree whose nodes live in a single slice and whose labels and values live
+// in a single byte buffer, the garbage collector has no per node pointer to scan.
+// Freed nodes are reused but replaced bytes are only reclaimed by Reset,
+// the arena holds up to 4G nodes and 4GiB of labels and values and Add fails once they are used up
+type ArenaRTree struct {
+	nodes []arenaNode
+	bytes []byte
+	free  []uint32
+	size  int
+	mu    sync.RWMutex
+}
+
+// NewArenaRTree returns an empty ArenaRTree with room for about sizeHint keys before growing
+func NewArenaRTree(sizeHint int) *ArenaRTree {
+	t := &ArenaRTree{}
+	t.nodes = make([]arenaNode, 1, 1+2*sizeHint)
+	t.bytes = make([]byte, 0, 32*sizeHint)
+	return t
+}
+
+// Reset drops every key at once, the arena memory is released to the garbage collector
+func (t *ArenaRTree) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nodes = make([]arenaNode, 1)
+	t.bytes = nil
+	t.free = nil
+	t.size = 0
+}
+
+// Len returns the number of stored keys
+func (t *ArenaRTree) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.size
+}
+
+func (t *ArenaRTree) edge(i uint32) []byte {
+	n := &t.nodes[i]
+	return t.bytes[n.label : n.label+n.labelLen]
+}
+
+func (t *ArenaRTree) valueOf(i uint32) string {
+	n := &t.nodes[i]
+	return string(t.bytes[n.value : n.value+n.valueLen])
+}
+
+// fits reports whether size more bytes and up to nodes more nodes stay within arenaMaxSize
+func (t *ArenaRTree) fits(size int, nodes int) bool {
+	if uint64(len(t.bytes))+uint64(size) > arenaMaxSize {
+		return false
+	}
+	return len(t.free) >= nodes || uint64(len(t.nodes))+uint64(nodes) <= arenaMaxSize
+}
+
+func (t *ArenaRTree) store(s string) (uint32, uint32) {
+	start := uint32(len(t.bytes))
+	t.bytes = append(t.bytes, s...)
+	return start, uint32(len(s))
+}
+
+func (t *ArenaRTree) setValue(i uint32, value string) {
+	start, length := t.store(value)
+	t.nodes[i].value, t.nodes[i].valueLen = start, length
+	t.nodes[i].isEnd = true
+}
+
+func (t *ArenaRTree) newNode(label string) uint32 {
+	node := arenaNode{}
+	node.label, node.labelLen = t.store(label)
+	if len(t.free) > 0 {
+		i := t.free[len(t.free)-1]
+		t.free = t.free[:len(t.free)-1]
+		t.nodes[i] = node
+		return i
+	}
+	t.nodes = append(t.nodes, node)
+	return uint32(len(t.nodes) - 1)
+}
+
+// findChild returns the child of parent whose edge starts with b and the sibling before it
+func (t *ArenaRTree) findChild(parent uint32, b byte) (uint32, uint32) {
+	prev := uint32(arenaNone)
+	for i := t.nodes[parent].child; i != arenaNone; i = t.nodes[i].next {
+		first := t.bytes[t.nodes[i].label]
+		if first == b {
+			return i, prev
+		}
+		if first > b {
+			break
+		}
+		prev = i
+	}
+	return arenaNone, prev
+}
+
+// link puts child after prev in the children of parent, first when prev is arenaNone
+func (t *ArenaRTree) link(parent uint32, prev uint32, child uint32) {
+	if prev == arenaNone {
+		t.nodes[child].next = t.nodes[parent].child
+		t.nodes[parent].child = child
+		return
+	}
+	t.nodes[child].next = t.nodes[prev].next
+	t.nodes[prev].next = child
+}
+
+func (t *ArenaRTree) unlink(parent uint32, prev uint32, child uint32) {
+	if prev == arenaNone {
+		t.nodes[parent].child = t.nodes[child].next
+	} else {
+		t.nodes[prev].next = t.nodes[child].next
+	}
+	t.nodes[child].next = arenaNone
+}
+
+// Add stores value for key, returns false for an invalid key or when the arena is full
+func (t *ArenaRTree) Add(key string, value string) bool {
+	if validateKey(key) != nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.fits(len(key)+len(value), 2) {
+		return false
+	}
+
+	node := uint32(0)
+	rest := key
+	for {
+		child, prev := t.findChild(node, rest[0])
+		if child == arenaNone {
+			child = t.newNode(rest)
+			t.setValue(child, value)
+			t.link(node, prev, child)
+			t.size++
+			return true
+		}
+
+		edge := t.edge(child)
+		shared := 0
+		for shared < len(edge) && shared < len(rest) && edge[shared] == rest[shared] {
+			shared++
+		}
+		if shared < len(edge) {
+			// Split the edge, the middle node reuses the first bytes of the child label
+			middle := t.newNode("")
+			t.nodes[middle].label, t.nodes[middle].labelLen = t.nodes[child].label, uint32(shared)
+			t.nodes[child].label += uint32(shared)
+			t.nodes[child].labelLen -= uint32(shared)
+			t.unlink(node, prev, child)
+			t.link(node, prev, middle)
+			t.nodes[middle].child = child
+			child = middle
+		}
+
+		rest = rest[shared:]
+		node = child
+		if rest == "" {
+			if !t.nodes[node].isEnd {
+				t.size++
+			}
+			t.setValue(node, value)
+			return true
+		}
+	}
+}
+
+// find returns the node reached by consuming all of key, arenaNone when key leaves the tree
+func (t *ArenaRTree) find(key string) uint32 {
+	node := uint32(0)
+	for key != "" {
+		child, _ := t.findChild(node, key[0])
+		if child == arenaNone {
+			return arenaNone
+		}
+		edge := t.edge(child)
+		if len(edge) > len(key) || string(edge) != key[:len(edge)] {
+			return arenaNone
+		}
+		key = key[len(edge):]
+		node = child
+	}
+	return node
+}
+
+func (t *ArenaRTree) Get(key string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	node := t.find(key)
+	if node == arenaNone || !t.nodes[node].isEnd {
+		return "", false
+	}
+	return t.valueOf(node), true
+}
+
+func (t *ArenaRTree) Contains(key string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	node := t.find(key)
+	return node != arenaNone && t.nodes[node].isEnd
+}
+
+func (t *ArenaRTree) Delete(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parent, prev, node := uint32(arenaNone), uint32(arenaNone), uint32(0)
+	rest := key
+	for rest != "" {
+		child, childPrev := t.findChild(node, rest[0])
+		if child == arenaNone {
+			return false
+		}
+		edge := t.edge(child)
+		if len(edge) > len(rest) || string(edge) != rest[:len(edge)] {
+			return false
+		}
+		rest = rest[len(edge):]
+		parent, prev, node = node, childPrev, child
+	}
+	if node == 0 || !t.nodes[node].isEnd {
+		return false
+	}
+	t.nodes[node].isEnd = false
+	t.size--
+
+	if t.nodes[node].child == arenaNone {
+		t.unlink(parent, prev, node)
+		t.free = append(t.free, node)
+		node = parent
+	}
+	if node != 0 && !t.nodes[node].isEnd {
+		t.merge(node)
+	}
+	return true
+}
+
+// merge folds the only child of node into it, the joined label is appended to the byte buffer.
+// A full arena keeps the two nodes apart, lookups do not depend on the merge
+func (t *ArenaRTree) merge(node uint32) {
+	child := t.nodes[node].child
+	if child == arenaNone || t.nodes[child].next != arenaNone {
+		return
+	}
+	if !t.fits(int(t.nodes[node].labelLen)+int(t.nodes[child].labelLen), 0) {
+		return
+	}
+	start := uint32(len(t.bytes))
+	t.bytes = append(t.bytes, t.edge(node)...)
+	t.bytes = append(t.bytes, t.edge(child)...)
+
+	merged := t.nodes[child]
+	merged.label, merged.labelLen = start, uint32(len(t.bytes))-start
+	merged.next = t.nodes[node].next
+	t.nodes[node] = merged
+	t.free = append(t.free, child)
+}
+
+// WalkPrefix calls fn for every key starting with prefix in key order until fn returns false
+func (t *ArenaRTree) WalkPrefix(prefix string, fn func(key string, value string) bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	node := uint32(0)
+	path := make([]byte, 0, len(prefix)+32)
+	rest := prefix
+	for rest != "" {
+		child, _ := t.findChild(node, rest[0])
+		if child == arenaNone {
+			return
+		}
+		edge := t.edge(child)
+		switch {
+		case len(edge) <= len(rest) && string(edge) == rest[:len(edge)]:
+			rest = rest[len(edge):]
+		case len(edge) > len(rest) && string(edge[:len(rest)]) == rest:
+			rest = ""
+		default:
+			return
+		}
+		path = append(path, edge...)
+		node = child
+	}
+	t.walkHandler(node, path, fn)
+}
+
+func (t *ArenaRTree) walkHandler(node uint32, path []byte, fn func(key string, value string) bool) bool {
+	if t.nodes[node].isEnd && !fn(string(path), t.valueOf(node)) {
+		return false
+	}
+	for child := t.nodes[node].child; child != arenaNone; child = t.nodes[child].next {
+		if !t.walkHandler(child, append(path, t.edge(child)...), fn) {
+			return false
+		}
+	}
+	return true
+}
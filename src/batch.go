@@ -0,0 +1,207 @@
+package src
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Entry is a key and its value
+type Entry struct {
+	Key   string
+	Value string
+}
+
+// batchFrame is a node on the path of the previous batch key and the length of its path
+type batchFrame struct {
+	node  *Node
+	depth int
+}
+
+// AddBatch stores every entry under a single lock, each key descends from the deepest node it shares
+// with the previous one so sorted input skips the common part of its paths. Entries are applied in
+// key order when hooks or watchers are registered, in input order otherwise.
+// Returns for each entry whether it was stored, when a key repeats the last entry wins
+func (tree *RTree) AddBatch(entries []Entry) []bool {
+	results := make([]bool, len(entries))
+
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	order := tree.batchOrder(len(entries), func(i int) string { return entries[i].Key })
+	for _, entry := range entries {
+		tree.dropIfExpired(entry.Key)
+	}
+
+	position := func(j int) int {
+		if order != nil {
+			return order[j]
+		}
+		return j
+	}
+	stack := []batchFrame{{node: tree.Root}}
+	previous := ""
+	for j := range entries {
+		i := position(j)
+		key, value := entries[i].Key, entries[i].Value
+		if validateKey(key) != nil {
+			continue
+		}
+		// Frames are only kept along the part of the path the next key shares,
+		// keys sharing nothing descend from the root as Add does
+		next := ""
+		if j+1 < len(entries) {
+			next = entries[position(j+1)].Key
+		}
+		stack = pushBatchFrames(popBatchFrames(stack, previous, key), key, next)
+		previous = key
+		top := stack[len(stack)-1]
+
+		event := Event{Type: EventPut, Key: key}
+		update := func(old string, exists bool) (string, bool) {
+			stored, store := tree.hooks.beforeAdd(key, value)
+			event.OldValue, event.NewValue, event.Existed = old, stored, exists
+			return stored, store
+		}
+		if top.depth == len(key) {
+			// The key ends on an existing node
+			if stored, store := update(top.node.Value, top.node.IsEnd); store {
+				top.node.IsEnd, top.node.Value = true, stored
+				results[i] = true
+			}
+		} else {
+			results[i] = tree.addHandler(key[top.depth:], update, top.node)
+		}
+		if results[i] {
+			tree.changed(event)
+		}
+	}
+	return results
+}
+
+// DeleteBatch removes every key under a single lock, each key descends from the deepest node it shares
+// with the previous one so sorted input skips the common part of its paths. Keys are removed in key
+// order when hooks or watchers are registered, in input order otherwise.
+// Returns for each key whether it was present, nodes left without a value are merged away
+func (tree *RTree) DeleteBatch(keys []string) []bool {
+	results := make([]bool, len(keys))
+
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	order := tree.batchOrder(len(keys), func(i int) string { return keys[i] })
+	for _, key := range keys {
+		tree.dropIfExpired(key)
+	}
+
+	stack := []batchFrame{{node: tree.Root}}
+	previous := ""
+	// merges holds the keys whose path was left with a node having a single child,
+	// they are merged once the batch is done as a later key may remove that child too
+	merges := []string{}
+	for j := range keys {
+		i := j
+		if order != nil {
+			i = order[j]
+		}
+		key := keys[i]
+		stack = pushBatchFrames(popBatchFrames(stack, previous, key), key, key)
+		previous = key
+
+		top := stack[len(stack)-1]
+		node := top.node
+		if top.depth != len(key) || node == tree.Root || !node.IsEnd || !tree.hooks.beforeDelete(key, node.Value) {
+			continue
+		}
+		event := Event{Type: EventDelete, Key: key, OldValue: node.Value, Existed: true}
+		node.IsEnd = false
+		node.Value = ""
+		results[i] = true
+		tree.changed(event)
+
+		// Remove the path bottom up while it holds nodes without a value or children
+		for len(stack) > 1 {
+			child := stack[len(stack)-1].node
+			if child.IsEnd || child.childCount() > 1 {
+				break
+			}
+			if child.childCount() == 1 {
+				merges = append(merges, key)
+				break
+			}
+			stack[len(stack)-2].node.removeChild(child.Key[0])
+			stack = stack[:len(stack)-1]
+		}
+	}
+	for _, key := range merges {
+		tree.compactPathHandler(tree.Root, key)
+	}
+	return results
+}
+
+// pruneChild removes the non-terminal child of node when it has no children
+// and merges it with its own child when it has only one
+func pruneChild(node *Node, child *Node) {
+	switch child.childCount() {
+	case 0:
+		node.removeChild(child.Key[0])
+	case 1:
+		child.eachChild(func(grandChild *Node) bool {
+			grandChild.Key = child.Key + grandChild.Key
+			node.setChild(grandChild)
+			return false
+		})
+	}
+}
+
+// batchOrder returns the positions of n batch keys sorted by key, keeping the input order of repeated keys,
+// or nil to apply them in input order because they are sorted already or nothing observes the order.
+// tree.mu must be held
+func (tree *RTree) batchOrder(n int, key func(i int) string) []int {
+	if !tree.observed() {
+		return nil
+	}
+	sorted := true
+	for i := 1; i < n && sorted; i++ {
+		sorted = key(i-1) <= key(i)
+	}
+	if sorted {
+		return nil
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a int, b int) int {
+		if c := strings.Compare(key(a), key(b)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	return order
+}
+
+// popBatchFrames drops the frames on the path of previous that are not on the path of key
+func popBatchFrames(stack []batchFrame, previous string, key string) []batchFrame {
+	for {
+		depth := stack[len(stack)-1].depth
+		if depth <= len(key) && key[:depth] == previous[:depth] {
+			return stack
+		}
+		stack = stack[:len(stack)-1]
+	}
+}
+
+// pushBatchFrames descends from the last frame along the edges followed by both key and next
+// and pushes a frame for every node it passes
+func pushBatchFrames(stack []batchFrame, key string, next string) []batchFrame {
+	top := stack[len(stack)-1]
+	node, depth := top.node, top.depth
+	for depth < len(key) && depth < len(next) {
+		child := node.child(key[depth])
+		if child == nil || !strings.HasPrefix(key[depth:], child.Key) || !strings.HasPrefix(next[depth:], child.Key) {
+			break
+		}
+		node, depth = child, depth+len(child.Key)
+		stack = append(stack, batchFrame{node: node, depth: depth})
+	}
+	return stack
+}
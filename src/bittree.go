@@ -0,0 +1,249 @@
+package src
+
+// BitNode is a node of a BitTree, Prefix holds the first Bits bits of every key below it
+// so the edge from the parent carries Bits - parent.Bits bits
+type BitNode struct {
+	Prefix   []byte
+	Bits     int
+	Value    string
+	IsEnd    bool
+	Children [2]*BitNode
+}
+
+// BitTree is a PATRICIA tree branching on single bits, keys may have any bit length
+type BitTree struct {
+	Root *BitNode
+	size int
+}
+
+// NewBitTree returns an empty BitTree
+func NewBitTree() *BitTree {
+	return &BitTree{
+		Root: &BitNode{},
+	}
+}
+
+func newBitNode(key []byte, bits int, value string) *BitNode {
+	return &BitNode{
+		Prefix: maskedBits(key, bits),
+		Bits:   bits,
+		Value:  value,
+		IsEnd:  true,
+	}
+}
+
+// Len returns the number of stored keys
+func (t *BitTree) Len() int {
+	return t.size
+}
+
+// NodeCount returns the number of nodes, root included
+func (t *BitTree) NodeCount() int {
+	return t.nodeCountHandler(t.Root)
+}
+
+func (t *BitTree) nodeCountHandler(node *BitNode) int {
+	if node == nil {
+		return 0
+	}
+	return 1 + t.nodeCountHandler(node.Children[0]) + t.nodeCountHandler(node.Children[1])
+}
+
+func (t *BitTree) Add(key string, value string) bool {
+	return t.AddBits([]byte(key), len(key)*8, value)
+}
+
+// AddBits stores value for the first bits bits of key
+func (t *BitTree) AddBits(key []byte, bits int, value string) bool {
+	if bits < 0 || bits > len(key)*8 {
+		return false
+	}
+
+	node := t.Root
+	for {
+		if node.Bits == bits {
+			if !node.IsEnd {
+				t.size++
+			}
+			node.IsEnd = true
+			node.Value = value
+			return true
+		}
+
+		direction := bitAt(key, node.Bits)
+		child := node.Children[direction]
+		if child == nil {
+			node.Children[direction] = newBitNode(key, bits, value)
+			t.size++
+			return true
+		}
+
+		diff := firstDiffBit(key, child.Prefix, node.Bits+1, min(child.Bits, bits))
+		if diff == child.Bits {
+			node = child
+			continue
+		}
+
+		// Split the edge at the first different bit
+		splitNode := &BitNode{
+			Prefix: maskedBits(key, diff),
+			Bits:   diff,
+		}
+		splitNode.Children[bitAt(child.Prefix, diff)] = child
+		node.Children[direction] = splitNode
+		if diff == bits {
+			splitNode.IsEnd = true
+			splitNode.Value = value
+		} else {
+			splitNode.Children[bitAt(key, diff)] = newBitNode(key, bits, value)
+		}
+		t.size++
+		return true
+	}
+}
+
+func (t *BitTree) Search(key string) *BitNode {
+	return t.SearchBits([]byte(key), len(key)*8)
+}
+
+// SearchBits returns the node stored for exactly the first bits bits of key
+func (t *BitTree) SearchBits(key []byte, bits int) *BitNode {
+	if bits < 0 || bits > len(key)*8 {
+		return nil
+	}
+
+	node := t.Root
+	for node.Bits < bits {
+		child := node.Children[bitAt(key, node.Bits)]
+		if child == nil || child.Bits > bits || firstDiffBit(key, child.Prefix, node.Bits+1, child.Bits) != child.Bits {
+			return nil
+		}
+		node = child
+	}
+	if !node.IsEnd {
+		return nil
+	}
+	return node
+}
+
+// LongestPrefix returns the bit length and value of the longest stored key that is a prefix of the first bits bits of key
+func (t *BitTree) LongestPrefix(key []byte, bits int) (int, string, bool) {
+	if bits < 0 || bits > len(key)*8 {
+		return 0, "", false
+	}
+
+	matchedBits, matchedValue, found := 0, "", false
+	node := t.Root
+	for {
+		if node.IsEnd {
+			matchedBits, matchedValue, found = node.Bits, node.Value, true
+		}
+		if node.Bits >= bits {
+			break
+		}
+		child := node.Children[bitAt(key, node.Bits)]
+		if child == nil || child.Bits > bits || firstDiffBit(key, child.Prefix, node.Bits+1, child.Bits) != child.Bits {
+			break
+		}
+		node = child
+	}
+	return matchedBits, matchedValue, found
+}
+
+func (t *BitTree) Delete(key string) bool {
+	return t.DeleteBits([]byte(key), len(key)*8)
+}
+
+// DeleteBits removes the first bits bits of key and merges the nodes left with a single child
+func (t *BitTree) DeleteBits(key []byte, bits int) bool {
+	if bits < 0 || bits > len(key)*8 {
+		return false
+	}
+
+	var grandParentNode, parentNode *BitNode
+	node := t.Root
+	for node.Bits < bits {
+		child := node.Children[bitAt(key, node.Bits)]
+		if child == nil || child.Bits > bits || firstDiffBit(key, child.Prefix, node.Bits+1, child.Bits) != child.Bits {
+			return false
+		}
+		grandParentNode = parentNode
+		parentNode = node
+		node = child
+	}
+	if !node.IsEnd {
+		return false
+	}
+
+	node.IsEnd = false
+	node.Value = ""
+	t.size--
+
+	if parentNode != nil {
+		t.compactBitNode(parentNode, node)
+	}
+	if grandParentNode != nil {
+		t.compactBitNode(grandParentNode, parentNode)
+	}
+	return true
+}
+
+// compactBitNode removes a non terminal node with less than two children from its parent
+func (t *BitTree) compactBitNode(parentNode *BitNode, node *BitNode) {
+	if node.IsEnd || (node.Children[0] != nil && node.Children[1] != nil) {
+		return
+	}
+	direction := bitAt(node.Prefix, parentNode.Bits)
+	if node.Children[0] != nil {
+		parentNode.Children[direction] = node.Children[0]
+	} else {
+		parentNode.Children[direction] = node.Children[1]
+	}
+}
+
+// Walk calls fn for every stored key in bit order until fn returns false
+func (t *BitTree) Walk(fn func(key []byte, bits int, value string) bool) {
+	t.walkHandler(t.Root, fn)
+}
+
+func (t *BitTree) walkHandler(node *BitNode, fn func(key []byte, bits int, value string) bool) bool {
+	if node == nil {
+		return true
+	}
+	if node.IsEnd {
+		key := make([]byte, len(node.Prefix))
+		copy(key, node.Prefix)
+		if !fn(key, node.Bits, node.Value) {
+			return false
+		}
+	}
+	return t.walkHandler(node.Children[0], fn) && t.walkHandler(node.Children[1], fn)
+}
+
+func bitAt(key []byte, i int) int {
+	return int(key[i/8]>>(7-i%8)) & 1
+}
+
+// firstDiffBit returns the first bit in [from, to) where a and b differ, or to
+func firstDiffBit(a []byte, b []byte, from int, to int) int {
+	for i := from; i < to; {
+		if i%8 == 0 && i+8 <= to && a[i/8] == b[i/8] {
+			i += 8
+			continue
+		}
+		if bitAt(a, i) != bitAt(b, i) {
+			return i
+		}
+		i++
+	}
+	return to
+}
+
+func maskedBits(key []byte, bits int) []byte {
+	masked := make([]byte, (bits+7)/8)
+	copy(masked, key)
+	if bits%8 != 0 {
+		masked[len(masked)-1] &= byte(0xff << (8 - bits%8))
+	}
+	return masked
+}
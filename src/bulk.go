@@ -0,0 +1,103 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// DuplicatePolicy tells BuildFromSorted what to do with a key repeated in its input
+type DuplicatePolicy int
+
+const (
+	// RejectDuplicates fails the build on the first repeated key
+	RejectDuplicates DuplicatePolicy = iota
+	// KeepFirst keeps the value of the first occurrence
+	KeepFirst
+	// KeepLast keeps the value of the last occurrence
+	KeepLast
+)
+
+var (
+	ErrUnsorted     = errors.New("rtree: keys are not sorted")
+	ErrDuplicateKey = errors.New("rtree: duplicate key")
+)
+
+type buildFrame struct {
+	node  *Node
+	depth int
+}
+
+// BuildFromSorted builds a compressed tree in one pass over keys sorted in ascending byte order.
+// The tree is built top-down: a stack holds the path to the previous key, each key climbs back to the
+// deepest node it shares with it, splits the edge it diverges in and hangs its leaf there, so a key
+// only touches the nodes it does not share with the previous one and no existing node is searched
+func BuildFromSorted(entries iter.Seq2[string, string], policy DuplicatePolicy) (*RTree, error) {
+	tree := NewRTree()
+	stack := []buildFrame{{node: tree.Root, depth: 0}}
+	previous := ""
+	first := true
+
+	for key, value := range entries {
+		if err := validateKey(key); err != nil {
+			return nil, err
+		}
+		if !first && key < previous {
+			return nil, fmt.Errorf("%w: %q after %q", ErrUnsorted, key, previous)
+		}
+		if !first && key == previous {
+			switch policy {
+			case KeepFirst:
+			case KeepLast:
+				stack[len(stack)-1].node.Value = value
+			default:
+				return nil, fmt.Errorf("%w: %q", ErrDuplicateKey, key)
+			}
+			continue
+		}
+
+		// Climb back to the deepest node whose path is shared with the previous key,
+		// comparing whole paths rather than scanning the shared prefix byte by byte
+		var popped *Node
+		for {
+			depth := stack[len(stack)-1].depth
+			if depth <= len(key) && key[:depth] == previous[:depth] {
+				break
+			}
+			popped = stack[len(stack)-1].node
+			stack = stack[:len(stack)-1]
+		}
+
+		// The shared part ends inside the edge of the popped node, split it
+		top := stack[len(stack)-1]
+		common := top.depth
+		if popped != nil {
+			split := commonPrefixLength(popped.Key, key[top.depth:])
+			if split > 0 {
+				middleNode := NewNode(popped.Key[:split], "")
+				middleNode.IsEnd = false
+				popped.Key = popped.Key[split:]
+				middleNode.setChild(popped)
+				top.node.setChild(middleNode)
+				common += split
+				stack = append(stack, buildFrame{node: middleNode, depth: common})
+			}
+		}
+
+		newNode := NewNode(key[common:], value)
+		stack[len(stack)-1].node.setChild(newNode)
+		stack = append(stack, buildFrame{node: newNode, depth: len(key)})
+
+		previous = key
+		first = false
+	}
+	return tree, nil
+}
+
+func commonPrefixLength(a string, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
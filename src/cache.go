@@ -0,0 +1,265 @@
+package src
+
+import (
+	"container/list"
+	"sync"
+)
+
+// EvictionPolicy chooses the entry a full CacheRTree drops
+type EvictionPolicy int
+
+const (
+	// EvictLRU drops the least recently used entry
+	EvictLRU EvictionPolicy = iota
+	// EvictLFU drops the least frequently used entry, the least recently used one among equals
+	EvictLFU
+)
+
+// CacheOptions configures a CacheRTree, a zero limit is no limit
+type CacheOptions struct {
+	Policy     EvictionPolicy
+	MaxEntries int
+	// MaxBytes bounds the sum of the key and value lengths
+	MaxBytes int64
+	// OnEvict is called for every evicted entry once the write that evicted it released the cache
+	OnEvict func(key string, value string)
+}
+
+// CacheRTree is a bounded tree evicting entries by recency or frequency of use,
+// Get and Add count as a use while prefix queries read the live entries without touching them
+type CacheRTree struct {
+	tree    *RTree
+	options CacheOptions
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	bytes   int64
+	// recent orders the entries from most to least recently used for EvictLRU
+	recent list.List
+	// frequencies holds a *cacheBucket per use count in ascending order for EvictLFU
+	frequencies list.List
+}
+
+type cacheEntry struct {
+	key     string
+	value   string
+	element *list.Element
+	bucket  *list.Element
+}
+
+// cacheBucket holds the entries used count times, most recently used first
+type cacheBucket struct {
+	count   int
+	entries list.List
+}
+
+// NewCacheRTree returns an empty CacheRTree
+func NewCacheRTree(options CacheOptions) *CacheRTree {
+	return &CacheRTree{
+		tree:    NewRTree(),
+		options: options,
+		entries: map[string]*cacheEntry{},
+	}
+}
+
+func (e *cacheEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// Add stores value for key and evicts entries until the cache is within its limits,
+// returns false for an invalid key or an entry larger than MaxBytes on its own
+func (c *CacheRTree) Add(key string, value string) bool {
+	if validateKey(key) != nil {
+		return false
+	}
+	if c.options.MaxBytes > 0 && int64(len(key)+len(value)) > c.options.MaxBytes {
+		return false
+	}
+
+	c.mu.Lock()
+	entry, exists := c.entries[key]
+	if exists {
+		c.bytes -= entry.size()
+		entry.value = value
+		c.use(entry)
+	} else {
+		entry = &cacheEntry{key: key, value: value}
+		c.entries[key] = entry
+		c.insert(entry)
+	}
+	c.bytes += entry.size()
+	c.tree.Add(key, value)
+	evicted := c.evict(entry)
+	c.mu.Unlock()
+
+	if c.options.OnEvict != nil {
+		for _, victim := range evicted {
+			c.options.OnEvict(victim.key, victim.value)
+		}
+	}
+	return true
+}
+
+func (c *CacheRTree) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, exists := c.entries[key]
+	if !exists {
+		return "", false
+	}
+	c.use(entry)
+	return entry.value, true
+}
+
+// Contains reports whether key is cached without counting as a use
+func (c *CacheRTree) Contains(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, exists := c.entries[key]
+	return exists
+}
+
+// Delete removes key without calling OnEvict
+func (c *CacheRTree) Delete(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, exists := c.entries[key]
+	if !exists {
+		return false
+	}
+	c.remove(entry)
+	c.tree.DeleteBatch([]string{key})
+	return true
+}
+
+// WalkPrefix calls fn for every cached key starting with prefix in key order until fn returns false.
+// The matches are read together under the cache lock and fn runs with no lock held, so it may call the cache
+func (c *CacheRTree) WalkPrefix(prefix string, fn func(key string, value string) bool) {
+	matches := []Entry{}
+	c.mu.Lock()
+	c.tree.WalkPrefix(prefix, func(key string, value string) bool {
+		matches = append(matches, Entry{Key: key, Value: value})
+		return true
+	})
+	c.mu.Unlock()
+
+	for _, match := range matches {
+		if !fn(match.Key, match.Value) {
+			return
+		}
+	}
+}
+
+// LongestPrefix returns the longest cached key that is a prefix of key
+func (c *CacheRTree) LongestPrefix(key string) (string, string, bool) {
+	return c.tree.LongestPrefix(key)
+}
+
+func (c *CacheRTree) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Bytes returns the sum of the key and value lengths of the cached entries
+func (c *CacheRTree) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+// evict drops entries other than kept until the limits hold and returns them, c.mu must be held
+func (c *CacheRTree) evict(kept *cacheEntry) []*cacheEntry {
+	evicted := []*cacheEntry{}
+	for c.overLimit() && len(c.entries) > 1 {
+		victim := c.victim(kept)
+		c.remove(victim)
+		evicted = append(evicted, victim)
+	}
+	if len(evicted) > 0 {
+		keys := make([]string, len(evicted))
+		for i, victim := range evicted {
+			keys[i] = victim.key
+		}
+		// DeleteBatch also prunes the nodes the victims leave behind
+		c.tree.DeleteBatch(keys)
+	}
+	return evicted
+}
+
+func (c *CacheRTree) overLimit() bool {
+	return (c.options.MaxEntries > 0 && len(c.entries) > c.options.MaxEntries) ||
+		(c.options.MaxBytes > 0 && c.bytes > c.options.MaxBytes)
+}
+
+// victim returns the entry the policy drops first, skipping kept
+func (c *CacheRTree) victim(kept *cacheEntry) *cacheEntry {
+	if c.options.Policy == EvictLFU {
+		for bucket := c.frequencies.Front(); bucket != nil; bucket = bucket.Next() {
+			for element := bucket.Value.(*cacheBucket).entries.Back(); element != nil; element = element.Prev() {
+				if entry := element.Value.(*cacheEntry); entry != kept {
+					return entry
+				}
+			}
+		}
+		return nil
+	}
+	for element := c.recent.Back(); element != nil; element = element.Prev() {
+		if entry := element.Value.(*cacheEntry); entry != kept {
+			return entry
+		}
+	}
+	return nil
+}
+
+// insert links a new entry as the most recently used one, with a use count of one
+func (c *CacheRTree) insert(entry *cacheEntry) {
+	if c.options.Policy != EvictLFU {
+		entry.element = c.recent.PushFront(entry)
+		return
+	}
+	first := c.frequencies.Front()
+	if first == nil || first.Value.(*cacheBucket).count != 1 {
+		first = c.frequencies.PushFront(&cacheBucket{count: 1})
+	}
+	c.link(entry, first)
+}
+
+// use records an access, moving entry to the front of the recency list or to the next frequency bucket
+func (c *CacheRTree) use(entry *cacheEntry) {
+	if c.options.Policy != EvictLFU {
+		c.recent.MoveToFront(entry.element)
+		return
+	}
+	count := entry.bucket.Value.(*cacheBucket).count
+	next := entry.bucket.Next()
+	if next == nil || next.Value.(*cacheBucket).count != count+1 {
+		next = c.frequencies.InsertAfter(&cacheBucket{count: count + 1}, entry.bucket)
+	}
+	c.unlink(entry)
+	c.link(entry, next)
+}
+
+func (c *CacheRTree) link(entry *cacheEntry, bucket *list.Element) {
+	entry.element = bucket.Value.(*cacheBucket).entries.PushFront(entry)
+	entry.bucket = bucket
+}
+
+func (c *CacheRTree) unlink(entry *cacheEntry) {
+	if c.options.Policy != EvictLFU {
+		c.recent.Remove(entry.element)
+		return
+	}
+	bucket := entry.bucket.Value.(*cacheBucket)
+	bucket.entries.Remove(entry.element)
+	if bucket.entries.Len() == 0 {
+		c.frequencies.Remove(entry.bucket)
+	}
+}
+
+// remove drops entry from the bookkeeping, the caller deletes it from the tree
+func (c *CacheRTree) remove(entry *cacheEntry) {
+	c.unlink(entry)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size()
+}
@@ -0,0 +1,343 @@
+package src
+
+// childIndex holds the children of a Node keyed by the first byte of their edge. The layout adapts
+// to the number of children as in an adaptive radix tree: 4 or 16 sorted bytes, a 256 byte index
+// into 48 slots or 256 direct slots, so finding a child never scans a map and a leaf holds no index
+type childIndex interface {
+	find(b byte) *Node
+	// set stores child under the first byte of its edge, replacing the child found there,
+	// and returns the index grown to the next layout when full
+	set(child *Node) childIndex
+	// remove drops the child under b and returns the index shrunk when sparse, nil once empty
+	remove(b byte) childIndex
+	len() int
+	// each calls fn for every child in edge order until fn returns false
+	each(fn func(b byte, child *Node) bool) bool
+}
+
+type children4 struct {
+	count uint8
+	keys  [4]byte
+	nodes [4]*Node
+}
+
+type children16 struct {
+	count uint8
+	keys  [16]byte
+	nodes [16]*Node
+}
+
+// children48 maps a byte to its slot + 1 in nodes, 0 means no child
+type children48 struct {
+	count uint8
+	index [256]uint8
+	nodes [48]*Node
+}
+
+type children256 struct {
+	count int
+	nodes [256]*Node
+}
+
+// searchKeys returns the position of b in the sorted keys, or where it would be inserted
+func searchKeys(keys []byte, b byte) (int, bool) {
+	for i, key := range keys {
+		if key >= b {
+			return i, key == b
+		}
+	}
+	return len(keys), false
+}
+
+func insertAt(keys []byte, nodes []*Node, count int, i int, child *Node) {
+	copy(keys[i+1:count+1], keys[i:count])
+	copy(nodes[i+1:count+1], nodes[i:count])
+	keys[i], nodes[i] = child.Key[0], child
+}
+
+func removeAt(keys []byte, nodes []*Node, count int, i int) {
+	copy(keys[i:count-1], keys[i+1:count])
+	copy(nodes[i:count-1], nodes[i+1:count])
+	nodes[count-1] = nil
+}
+
+func eachSorted(keys []byte, nodes []*Node, fn func(b byte, child *Node) bool) bool {
+	for i, key := range keys {
+		if !fn(key, nodes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *children4) find(b byte) *Node {
+	for i := 0; i < int(c.count); i++ {
+		if c.keys[i] == b {
+			return c.nodes[i]
+		}
+	}
+	return nil
+}
+
+func (c *children4) set(child *Node) childIndex {
+	i, found := searchKeys(c.keys[:c.count], child.Key[0])
+	if found {
+		c.nodes[i] = child
+		return c
+	}
+	if int(c.count) == len(c.keys) {
+		grown := &children16{count: c.count}
+		copy(grown.keys[:], c.keys[:])
+		copy(grown.nodes[:], c.nodes[:])
+		return grown.set(child)
+	}
+	insertAt(c.keys[:], c.nodes[:], int(c.count), i, child)
+	c.count++
+	return c
+}
+
+func (c *children4) remove(b byte) childIndex {
+	i, found := searchKeys(c.keys[:c.count], b)
+	if !found {
+		return c
+	}
+	removeAt(c.keys[:], c.nodes[:], int(c.count), i)
+	c.count--
+	if c.count == 0 {
+		return nil
+	}
+	return c
+}
+
+func (c *children4) len() int {
+	return int(c.count)
+}
+
+func (c *children4) each(fn func(b byte, child *Node) bool) bool {
+	return eachSorted(c.keys[:c.count], c.nodes[:c.count], fn)
+}
+
+func (c *children16) find(b byte) *Node {
+	// Keys are sorted, stop at the first one past b
+	for i := 0; i < int(c.count) && c.keys[i] <= b; i++ {
+		if c.keys[i] == b {
+			return c.nodes[i]
+		}
+	}
+	return nil
+}
+
+func (c *children16) set(child *Node) childIndex {
+	i, found := searchKeys(c.keys[:c.count], child.Key[0])
+	if found {
+		c.nodes[i] = child
+		return c
+	}
+	if int(c.count) == len(c.keys) {
+		grown := &children48{count: c.count}
+		for j := range c.keys {
+			grown.nodes[j] = c.nodes[j]
+			grown.index[c.keys[j]] = uint8(j + 1)
+		}
+		return grown.set(child)
+	}
+	insertAt(c.keys[:], c.nodes[:], int(c.count), i, child)
+	c.count++
+	return c
+}
+
+func (c *children16) remove(b byte) childIndex {
+	i, found := searchKeys(c.keys[:c.count], b)
+	if !found {
+		return c
+	}
+	removeAt(c.keys[:], c.nodes[:], int(c.count), i)
+	c.count--
+	if c.count > 3 {
+		return c
+	}
+	shrunk := &children4{count: c.count}
+	copy(shrunk.keys[:], c.keys[:c.count])
+	copy(shrunk.nodes[:], c.nodes[:c.count])
+	return shrunk
+}
+
+func (c *children16) len() int {
+	return int(c.count)
+}
+
+func (c *children16) each(fn func(b byte, child *Node) bool) bool {
+	return eachSorted(c.keys[:c.count], c.nodes[:c.count], fn)
+}
+
+func (c *children48) find(b byte) *Node {
+	if c.index[b] == 0 {
+		return nil
+	}
+	return c.nodes[c.index[b]-1]
+}
+
+func (c *children48) set(child *Node) childIndex {
+	b := child.Key[0]
+	if c.index[b] != 0 {
+		c.nodes[c.index[b]-1] = child
+		return c
+	}
+	if int(c.count) == len(c.nodes) {
+		grown := &children256{count: int(c.count)}
+		for i, slot := range c.index {
+			if slot != 0 {
+				grown.nodes[i] = c.nodes[slot-1]
+			}
+		}
+		return grown.set(child)
+	}
+	slot := 0
+	for c.nodes[slot] != nil {
+		slot++
+	}
+	c.nodes[slot] = child
+	c.index[b] = uint8(slot + 1)
+	c.count++
+	return c
+}
+
+func (c *children48) remove(b byte) childIndex {
+	if c.index[b] == 0 {
+		return c
+	}
+	c.nodes[c.index[b]-1] = nil
+	c.index[b] = 0
+	c.count--
+	if c.count > 12 {
+		return c
+	}
+	shrunk := &children16{}
+	for i, slot := range c.index {
+		if slot != 0 {
+			shrunk.keys[shrunk.count] = byte(i)
+			shrunk.nodes[shrunk.count] = c.nodes[slot-1]
+			shrunk.count++
+		}
+	}
+	return shrunk
+}
+
+func (c *children48) len() int {
+	return int(c.count)
+}
+
+func (c *children48) each(fn func(b byte, child *Node) bool) bool {
+	for i, slot := range c.index {
+		if slot != 0 && !fn(byte(i), c.nodes[slot-1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *children256) find(b byte) *Node {
+	return c.nodes[b]
+}
+
+func (c *children256) set(child *Node) childIndex {
+	b := child.Key[0]
+	if c.nodes[b] == nil {
+		c.count++
+	}
+	c.nodes[b] = child
+	return c
+}
+
+func (c *children256) remove(b byte) childIndex {
+	if c.nodes[b] == nil {
+		return c
+	}
+	c.nodes[b] = nil
+	c.count--
+	if c.count > 37 {
+		return c
+	}
+	shrunk := &children48{}
+	for i, child := range c.nodes {
+		if child != nil {
+			shrunk.nodes[shrunk.count] = child
+			shrunk.index[i] = shrunk.count + 1
+			shrunk.count++
+		}
+	}
+	return shrunk
+}
+
+func (c *children256) len() int {
+	return c.count
+}
+
+func (c *children256) each(fn func(b byte, child *Node) bool) bool {
+	for i, child := range c.nodes {
+		if child != nil && !fn(byte(i), child) {
+			return false
+		}
+	}
+	return true
+}
+
+// Children returns the children of the node in edge order
+func (n *Node) Children() []*Node {
+	children := make([]*Node, 0, n.childCount())
+	n.eachChild(func(child *Node) bool {
+		children = append(children, child)
+		return true
+	})
+	return children
+}
+
+// Child returns the child whose edge is key
+func (n *Node) Child(key string) (*Node, bool) {
+	if key == "" {
+		return nil, false
+	}
+	child := n.child(key[0])
+	if child == nil || child.Key != key {
+		return nil, false
+	}
+	return child, true
+}
+
+// child returns the child whose edge starts with b
+func (n *Node) child(b byte) *Node {
+	if n.children == nil {
+		return nil
+	}
+	return n.children.find(b)
+}
+
+// setChild adds child, replacing the child whose edge starts with the same byte
+func (n *Node) setChild(child *Node) {
+	if n.children == nil {
+		n.children = &children4{}
+	}
+	n.children = n.children.set(child)
+}
+
+func (n *Node) removeChild(b byte) {
+	if n.children != nil {
+		n.children = n.children.remove(b)
+	}
+}
+
+func (n *Node) childCount() int {
+	if n.children == nil {
+		return 0
+	}
+	return n.children.len()
+}
+
+func (n *Node) eachChild(fn func(child *Node) bool) bool {
+	if n.children == nil {
+		return true
+	}
+	return n.children.each(func(_ byte, child *Node) bool {
+		return fn(child)
+	})
+}
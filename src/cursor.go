@@ -0,0 +1,98 @@
+package src
+
+import "strings"
+
+// Cursor is a read-only position on a node of an RTree, it gives access to the tree
+// layout without exposing the node so callers can not corrupt the child indexes
+type Cursor struct {
+	tree *RTree
+	node *Node
+	key  string
+}
+
+// Cursor returns a Cursor on the root of the tree
+func (tree *RTree) Cursor() *Cursor {
+	return &Cursor{
+		tree: tree,
+		node: tree.Root,
+	}
+}
+
+// Seek returns a Cursor on the node whose path is exactly key, terminal or not,
+// a node left with expired keys only is not found
+func (tree *RTree) Seek(key string) (*Cursor, bool) {
+	tree.mu.RLock()
+	defer tree.mu.RUnlock()
+
+	node := tree.Root
+	offset := 0
+	for offset < len(key) {
+		next := node.child(key[offset])
+		if next == nil || !strings.HasPrefix(key[offset:], next.Key) {
+			return nil, false
+		}
+		offset += len(next.Key)
+		node = next
+	}
+	if node != tree.Root && !tree.hasLiveKey(key, node) {
+		return nil, false
+	}
+	return &Cursor{tree: tree, node: node, key: key}, true
+}
+
+// IsRoot reports whether the cursor is on the root of the tree
+func (c *Cursor) IsRoot() bool {
+	return c.node == c.tree.Root
+}
+
+// Key returns the full key from the root to the node
+func (c *Cursor) Key() string {
+	return c.key
+}
+
+// Edge returns the label of the edge leading to the node, "" on the root
+func (c *Cursor) Edge() string {
+	if c.IsRoot() {
+		return ""
+	}
+	c.tree.mu.RLock()
+	defer c.tree.mu.RUnlock()
+	return c.node.Key
+}
+
+// Value returns the value of the key ending on the node, "" when none does or it expired
+func (c *Cursor) Value() string {
+	c.tree.mu.RLock()
+	defer c.tree.mu.RUnlock()
+	if c.tree.isExpired(c.key) {
+		return ""
+	}
+	return c.node.Value
+}
+
+// IsEnd reports whether a key that has not expired ends on the node
+func (c *Cursor) IsEnd() bool {
+	c.tree.mu.RLock()
+	defer c.tree.mu.RUnlock()
+	return c.node.IsEnd && !c.tree.isExpired(c.key)
+}
+
+// Children returns a Cursor for every child sorted by edge, skipping children left with expired keys only
+func (c *Cursor) Children() []*Cursor {
+	c.tree.mu.RLock()
+	defer c.tree.mu.RUnlock()
+
+	cursors := []*Cursor{}
+	for _, child := range c.node.Children() {
+		key := c.key + child.Key
+		if !c.tree.hasLiveKey(key, child) {
+			continue
+		}
+		cursors = append(cursors, &Cursor{
+			tree: c.tree,
+			node: child,
+			key:  key,
+		})
+	}
+	return cursors
+}
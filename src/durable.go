@@ -0,0 +1,296 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var ErrClosed = errors.New("rtree: durable tree is closed")
+
+// DurableOptions configures a DurableRTree
+type DurableOptions struct {
+	// NoSync skips the fsync after every record, a crash can then lose the last acknowledged writes
+	NoSync bool
+	// SnapshotBytes starts a background snapshot once the log grows past it, 0 disables it
+	SnapshotBytes int64
+	// SnapshotEntries starts a background snapshot once the log holds that many records, 0 disables it
+	SnapshotEntries int
+}
+
+// DurableRTree is an RTree whose writes are appended to a write-ahead log before being applied,
+// opening it loads the snapshot and replays the log so acknowledged writes survive a restart
+type DurableRTree struct {
+	tree    *RTree
+	options DurableOptions
+	path    string
+
+	mu          sync.Mutex
+	file        *os.File
+	offset      int64
+	entries     int
+	closing     bool
+	running     bool
+	snapshotErr error
+
+	snapshotting sync.Mutex
+	background   sync.WaitGroup
+}
+
+// OpenDurableRTree opens or creates the log at path, loads the snapshot kept at path + ".snap"
+// and replays the log on top of it, a torn last record is truncated away
+// while a damaged record followed by more records fails the open and leaves the log untouched
+func OpenDurableRTree(path string, options DurableOptions) (*DurableRTree, error) {
+	d := &DurableRTree{
+		tree:    NewRTree(),
+		options: options,
+		path:    path,
+	}
+
+	// Leftovers of a snapshot or a compaction interrupted by a crash
+	os.Remove(d.snapshotPath() + ".tmp")
+	os.Remove(path + ".tmp")
+
+	if err := loadSnapshot(d.snapshotPath(), d.apply); err != nil {
+		return nil, fmt.Errorf("rtree: load %s: %w", d.snapshotPath(), err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	d.file = file
+	if err := d.replay(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *DurableRTree) snapshotPath() string {
+	return d.path + ".snap"
+}
+
+func (d *DurableRTree) replay() error {
+	valid, err := readWALRecords(d.file, func(record walRecord) error {
+		d.entries++
+		return d.apply(record)
+	})
+	if err != nil {
+		return fmt.Errorf("rtree: replay %s: %w", d.path, err)
+	}
+	return d.truncate(valid)
+}
+
+func (d *DurableRTree) truncate(offset int64) error {
+	if err := d.file.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := d.file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	d.offset = offset
+	return nil
+}
+
+func (d *DurableRTree) apply(record walRecord) error {
+	switch record.op {
+	case walPut:
+		if !d.tree.Add(record.key, record.value) {
+			return fmt.Errorf("rtree: invalid key %q in log", record.key)
+		}
+	case walDelete:
+		d.tree.Delete(record.key)
+	}
+	return nil
+}
+
+func (d *DurableRTree) append(record walRecord) error {
+	if d.file == nil {
+		return ErrClosed
+	}
+	frame := encodeWALRecord(record)
+	if _, err := d.file.Write(frame); err != nil {
+		// Drop the partial record so later writes are not hidden behind it
+		d.truncate(d.offset)
+		return err
+	}
+	if !d.options.NoSync {
+		if err := d.file.Sync(); err != nil {
+			// The write is reported as failed so it must not come back on replay
+			d.truncate(d.offset)
+			return err
+		}
+	}
+	d.offset += int64(len(frame))
+	d.entries++
+	d.maybeSnapshot()
+	return nil
+}
+
+// maybeSnapshot starts a background snapshot once a threshold is crossed, d.mu must be held
+func (d *DurableRTree) maybeSnapshot() {
+	if d.running || d.closing {
+		return
+	}
+	overBytes := d.options.SnapshotBytes > 0 && d.offset >= d.options.SnapshotBytes
+	overEntries := d.options.SnapshotEntries > 0 && d.entries >= d.options.SnapshotEntries
+	if !overBytes && !overEntries {
+		return
+	}
+
+	d.running = true
+	d.background.Add(1)
+	go func() {
+		defer d.background.Done()
+		err := d.Snapshot()
+		d.mu.Lock()
+		d.running = false
+		d.snapshotErr = err
+		d.mu.Unlock()
+	}()
+}
+
+// Add logs and stores value for key
+func (d *DurableRTree) Add(key string, value string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	record := walRecord{op: walPut, key: key, value: value}
+	if err := d.append(record); err != nil {
+		return err
+	}
+	return d.apply(record)
+}
+
+// Delete logs and removes key, missing keys are not logged
+func (d *DurableRTree) Delete(key string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.tree.Contains(key) {
+		return false, nil
+	}
+	record := walRecord{op: walDelete, key: key}
+	if err := d.append(record); err != nil {
+		return false, err
+	}
+	return true, d.apply(record)
+}
+
+func (d *DurableRTree) Get(key string) (string, bool) {
+	return d.tree.Get(key)
+}
+
+func (d *DurableRTree) Contains(key string) bool {
+	return d.tree.Contains(key)
+}
+
+func (d *DurableRTree) WalkPrefix(prefix string, fn func(key string, value string) bool) {
+	d.tree.WalkPrefix(prefix, fn)
+}
+
+// LogSize returns the size in bytes of the log written since the last snapshot
+func (d *DurableRTree) LogSize() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.offset
+}
+
+// SnapshotErr returns the error of the last background snapshot
+func (d *DurableRTree) SnapshotErr() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.snapshotErr
+}
+
+// Snapshot writes the whole tree to the snapshot file and drops the log records it covers,
+// writes are blocked only while the entries are collected and while the log tail is copied
+func (d *DurableRTree) Snapshot() error {
+	d.snapshotting.Lock()
+	defer d.snapshotting.Unlock()
+
+	d.mu.Lock()
+	if d.file == nil {
+		d.mu.Unlock()
+		return ErrClosed
+	}
+	entries := []walRecord{}
+	d.tree.WalkPrefix("", func(key string, value string) bool {
+		entries = append(entries, walRecord{op: walPut, key: key, value: value})
+		return true
+	})
+	covered := d.offset
+	coveredEntries := d.entries
+	d.mu.Unlock()
+
+	if err := writeSnapshot(d.snapshotPath(), entries); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		return ErrClosed
+	}
+	return d.compactLog(covered, coveredEntries)
+}
+
+// compactLog replaces the log with its records past covered, d.mu must be held,
+// a crash before the rename leaves the full log which replays safely over the new snapshot
+func (d *DurableRTree) compactLog(covered int64, coveredEntries int) error {
+	tail := make([]byte, d.offset-covered)
+	if _, err := d.file.ReadAt(tail, covered); err != nil {
+		return err
+	}
+
+	tmpPath := d.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(tail)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, d.path)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	syncDir(filepath.Dir(d.path))
+
+	d.file.Close()
+	d.file = file
+	d.offset = int64(len(tail))
+	d.entries -= coveredEntries
+	return nil
+}
+
+// Close waits for a running snapshot, then syncs and closes the log, the tree can not be written afterwards
+func (d *DurableRTree) Close() error {
+	d.mu.Lock()
+	d.closing = true
+	d.mu.Unlock()
+	d.background.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		return ErrClosed
+	}
+	err := d.file.Sync()
+	if closeErr := d.file.Close(); err == nil {
+		err = closeErr
+	}
+	d.file = nil
+	return err
+}
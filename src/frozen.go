@@ -0,0 +1,322 @@
+package src
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// frozenMagic starts every frozen file and the file ends with the little endian uint32 offset of the root node.
+// Nodes are written children first, each one as a flags byte, the uvarint edge length and edge,
+// the uvarint value length and value, the uvarint child count and a child table
+// of first edge byte and uint32 offset entries sorted by byte
+const frozenMagic = "RTFROZ1\n"
+
+const (
+	frozenFooterSize     = 4
+	frozenChildEntrySize = 5
+	frozenEndFlag        = 1
+)
+
+var ErrCorruptFrozen = errors.New("rtree: corrupt frozen tree")
+
+// FrozenRTree is a read-only tree answering lookups directly on the bytes of a file mapped in memory
+type FrozenRTree struct {
+	data []byte
+	root uint32
+}
+
+type frozenNode struct {
+	offset     uint32
+	isEnd      bool
+	edge       []byte
+	value      []byte
+	childCount int
+	children   []byte
+}
+
+// WriteFrozen writes tree in the frozen layout
+func WriteFrozen(tree *RTree, w io.Writer) error {
+	tree.mu.RLock()
+	defer tree.mu.RUnlock()
+
+	writer := bufio.NewWriter(w)
+	if _, err := writer.WriteString(frozenMagic); err != nil {
+		return err
+	}
+
+	offset := uint32(len(frozenMagic))
+	root, _, err := writeFrozenNode(writer, tree, tree.Root, "", "", &offset)
+	if err != nil {
+		return err
+	}
+	footer := binary.LittleEndian.AppendUint32(nil, root)
+	if _, err := writer.Write(footer); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// writeFrozenNode writes the live part of the subtree of node after its children and returns its offset,
+// expired keys are left out and a subtree without a live key is not written, reported by live false
+func writeFrozenNode(writer *bufio.Writer, tree *RTree, node *Node, edge string, path string, offset *uint32) (uint32, bool, error) {
+	children := []*Node{}
+	childOffsets := []uint32{}
+	for _, child := range node.Children() {
+		childOffset, live, err := writeFrozenNode(writer, tree, child, child.Key, path+child.Key, offset)
+		if err != nil {
+			return 0, false, err
+		}
+		if live {
+			children = append(children, child)
+			childOffsets = append(childOffsets, childOffset)
+		}
+	}
+	isEnd := node.IsEnd && node != tree.Root && !tree.isExpired(path)
+	if !isEnd && len(children) == 0 && node != tree.Root {
+		return 0, false, nil
+	}
+	value := ""
+	if isEnd {
+		value = node.Value
+	}
+
+	buffer := make([]byte, 0, 1+3*binary.MaxVarintLen64+len(edge)+len(value)+len(children)*frozenChildEntrySize)
+	flags := byte(0)
+	if isEnd {
+		flags |= frozenEndFlag
+	}
+	buffer = append(buffer, flags)
+	buffer = binary.AppendUvarint(buffer, uint64(len(edge)))
+	buffer = append(buffer, edge...)
+	buffer = binary.AppendUvarint(buffer, uint64(len(value)))
+	buffer = append(buffer, value...)
+	buffer = binary.AppendUvarint(buffer, uint64(len(children)))
+	for i, child := range children {
+		buffer = append(buffer, child.Key[0])
+		buffer = binary.LittleEndian.AppendUint32(buffer, childOffsets[i])
+	}
+
+	if uint64(*offset)+uint64(len(buffer)) > 1<<32-1 {
+		return 0, false, errors.New("rtree: frozen tree larger than 4GiB")
+	}
+	if _, err := writer.Write(buffer); err != nil {
+		return 0, false, err
+	}
+	nodeOffset := *offset
+	*offset += uint32(len(buffer))
+	return nodeOffset, true, nil
+}
+
+// WriteFrozenFile writes tree in the frozen layout to a temporary file renamed over path once synced
+func WriteFrozenFile(tree *RTree, path string) error {
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	err = WriteFrozen(tree, file)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// OpenFrozen maps the frozen file at path in memory
+func OpenFrozen(path string) (*FrozenRTree, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < int64(len(frozenMagic)+frozenFooterSize) || info.Size() > 1<<32-1 {
+		return nil, ErrCorruptFrozen
+	}
+	data, err := mapFile(file, int(info.Size()))
+	if err != nil {
+		return nil, err
+	}
+
+	f := &FrozenRTree{
+		data: data,
+		root: binary.LittleEndian.Uint32(data[len(data)-frozenFooterSize:]),
+	}
+	if !bytes.Equal(data[:len(frozenMagic)], []byte(frozenMagic)) || int(f.root) >= len(data)-frozenFooterSize {
+		f.Close()
+		return nil, ErrCorruptFrozen
+	}
+	if _, ok := f.node(f.root); !ok {
+		f.Close()
+		return nil, ErrCorruptFrozen
+	}
+	return f, nil
+}
+
+// Close unmaps the file, the tree can not be read afterwards
+func (f *FrozenRTree) Close() error {
+	if f.data == nil {
+		return nil
+	}
+	err := unmapFile(f.data)
+	f.data = nil
+	return err
+}
+
+// node decodes the node at offset, ok is false when the bytes are out of bounds
+func (f *FrozenRTree) node(offset uint32) (frozenNode, bool) {
+	if offset < uint32(len(frozenMagic)) || int(offset) >= len(f.data)-frozenFooterSize {
+		return frozenNode{}, false
+	}
+	data := f.data[offset : len(f.data)-frozenFooterSize]
+	node := frozenNode{offset: offset, isEnd: data[0]&frozenEndFlag != 0}
+	data = data[1:]
+
+	fields := [2][]byte{}
+	for i := range fields {
+		length, n := binary.Uvarint(data)
+		if n <= 0 || length > uint64(len(data)-n) {
+			return frozenNode{}, false
+		}
+		fields[i] = data[n : n+int(length)]
+		data = data[n+int(length):]
+	}
+	node.edge, node.value = fields[0], fields[1]
+
+	count, n := binary.Uvarint(data)
+	if n <= 0 || count > uint64(len(data)-n)/frozenChildEntrySize {
+		return frozenNode{}, false
+	}
+	node.childCount = int(count)
+	node.children = data[n : n+int(count)*frozenChildEntrySize]
+	return node, true
+}
+
+// child returns the child whose edge starts with b
+func (f *FrozenRTree) child(node frozenNode, b byte) (frozenNode, bool) {
+	i := sort.Search(node.childCount, func(i int) bool {
+		return node.children[i*frozenChildEntrySize] >= b
+	})
+	if i == node.childCount || node.children[i*frozenChildEntrySize] != b {
+		return frozenNode{}, false
+	}
+	return f.childAt(node, i)
+}
+
+// childAt returns the i-th child, children are written first so a child offset
+// must be lower than its parent one, which also rules out cycles in a damaged file
+func (f *FrozenRTree) childAt(node frozenNode, i int) (frozenNode, bool) {
+	offset := binary.LittleEndian.Uint32(node.children[i*frozenChildEntrySize+1:])
+	if offset >= node.offset {
+		return frozenNode{}, false
+	}
+	return f.node(offset)
+}
+
+func (f *FrozenRTree) Get(key string) (string, bool) {
+	if f.data == nil {
+		return "", false
+	}
+	node, ok := f.node(f.root)
+	for ok && key != "" {
+		node, ok = f.child(node, key[0])
+		if !ok || len(node.edge) > len(key) || string(node.edge) != key[:len(node.edge)] {
+			return "", false
+		}
+		key = key[len(node.edge):]
+	}
+	if !ok || !node.isEnd {
+		return "", false
+	}
+	return string(node.value), true
+}
+
+func (f *FrozenRTree) Contains(key string) bool {
+	_, exists := f.Get(key)
+	return exists
+}
+
+func (f *FrozenRTree) LongestPrefix(key string) (string, string, bool) {
+	matchedKey, matchedValue, found := "", "", false
+	if f.data == nil {
+		return matchedKey, matchedValue, found
+	}
+	node, ok := f.node(f.root)
+	offset := 0
+	for ok && offset < len(key) {
+		node, ok = f.child(node, key[offset])
+		if !ok || len(node.edge) > len(key)-offset || string(node.edge) != key[offset:offset+len(node.edge)] {
+			break
+		}
+		offset += len(node.edge)
+		if node.isEnd {
+			matchedKey, matchedValue, found = key[:offset], string(node.value), true
+		}
+	}
+	return matchedKey, matchedValue, found
+}
+
+// WalkPrefix calls fn for every key starting with prefix in key order until fn returns false
+func (f *FrozenRTree) WalkPrefix(prefix string, fn func(key string, value string) bool) {
+	if f.data == nil {
+		return
+	}
+	node, ok := f.node(f.root)
+	path := ""
+	rest := prefix
+	for ok && rest != "" {
+		node, ok = f.child(node, rest[0])
+		if !ok {
+			return
+		}
+		edge := string(node.edge)
+		switch {
+		case len(edge) <= len(rest) && edge == rest[:len(edge)]:
+			rest = rest[len(edge):]
+		case len(edge) > len(rest) && edge[:len(rest)] == rest:
+			rest = ""
+		default:
+			return
+		}
+		path += edge
+	}
+	if ok {
+		f.walkHandler(node, path, fn)
+	}
+}
+
+func (f *FrozenRTree) walkHandler(node frozenNode, path string, fn func(key string, value string) bool) bool {
+	if node.isEnd && !fn(path, string(node.value)) {
+		return false
+	}
+	for i := 0; i < node.childCount; i++ {
+		child, ok := f.childAt(node, i)
+		if !ok {
+			return false
+		}
+		if !f.walkHandler(child, path+string(child.edge), fn) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package src
+
+// mutationHooks holds the callbacks registered on an RTree, they run in registration order
+// with the tree locked and must not call the tree
+type mutationHooks struct {
+	beforeAddHooks    []func(key string, value string) (string, bool)
+	afterAddHooks     []func(event Event)
+	beforeDeleteHooks []func(key string, value string) bool
+	afterDeleteHooks  []func(event Event)
+}
+
+// BeforeAdd registers hook to run before a value is stored by Add, Update and its variants or AddBatch,
+// hook returns the value to store, possibly transformed, or false to veto the write
+func (tree *RTree) BeforeAdd(hook func(key string, value string) (string, bool)) {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	tree.hooks.beforeAddHooks = append(tree.hooks.beforeAddHooks, hook)
+}
+
+// AfterAdd registers hook to run once a value has been stored
+func (tree *RTree) AfterAdd(hook func(event Event)) {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	tree.hooks.afterAddHooks = append(tree.hooks.afterAddHooks, hook)
+}
+
+// BeforeDelete registers hook to run before a key is removed by Delete or DeleteBatch,
+// hook receives the current value and returns false to veto the removal
+func (tree *RTree) BeforeDelete(hook func(key string, value string) bool) {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	tree.hooks.beforeDeleteHooks = append(tree.hooks.beforeDeleteHooks, hook)
+}
+
+// AfterDelete registers hook to run once a key has been removed
+func (tree *RTree) AfterDelete(hook func(event Event)) {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	tree.hooks.afterDeleteHooks = append(tree.hooks.afterDeleteHooks, hook)
+}
+
+// beforeAdd passes value through every BeforeAdd hook, stopping at the first veto
+func (h *mutationHooks) beforeAdd(key string, value string) (string, bool) {
+	for _, hook := range h.beforeAddHooks {
+		var ok bool
+		if value, ok = hook(key, value); !ok {
+			return value, false
+		}
+	}
+	return value, true
+}
+
+func (h *mutationHooks) beforeDelete(key string, value string) bool {
+	for _, hook := range h.beforeDeleteHooks {
+		if !hook(key, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// observed reports whether a hook or a watcher sees the writes one at a time, tree.mu must be held
+func (tree *RTree) observed() bool {
+	h := &tree.hooks
+	return len(h.beforeAddHooks)+len(h.afterAddHooks)+len(h.beforeDeleteHooks)+len(h.afterDeleteHooks) > 0 ||
+		tree.watches != nil
+}
+
+// changed runs the after hooks for event then hands it to the watchers, tree.mu must be held,
+// any write also drops the expiry of the key
+func (tree *RTree) changed(event Event) {
+	if tree.expiries != nil {
+		delete(tree.expiries, event.Key)
+	}
+	hooks := tree.hooks.afterAddHooks
+	if event.Type == EventDelete {
+		hooks = tree.hooks.afterDeleteHooks
+	}
+	for _, hook := range hooks {
+		hook(event)
+	}
+	if tree.watches != nil {
+		tree.watches.notify(event)
+	}
+}
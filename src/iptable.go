@@ -0,0 +1,109 @@
+package src
+
+import "net/netip"
+
+// IPTable maps CIDR prefixes to values and resolves addresses by longest prefix match,
+// IPv4 and IPv6 prefixes are kept in separate bit trees
+type IPTable struct {
+	ipv4 *BitTree
+	ipv6 *BitTree
+}
+
+// NewIPTable returns an empty IPTable
+func NewIPTable() *IPTable {
+	return &IPTable{
+		ipv4: NewBitTree(),
+		ipv6: NewBitTree(),
+	}
+}
+
+// Len returns the number of stored prefixes
+func (t *IPTable) Len() int {
+	return t.ipv4.Len() + t.ipv6.Len()
+}
+
+func (t *IPTable) treeFor(addr netip.Addr) *BitTree {
+	if addr.Is4() {
+		return t.ipv4
+	}
+	return t.ipv6
+}
+
+// unmap turns an IPv4-mapped IPv6 prefix into the IPv4 prefix Lookup matches it against,
+// a mapped prefix shorter than 96 bits also covers non IPv4 addresses and is rejected
+func unmap(prefix netip.Prefix) (netip.Prefix, bool) {
+	if !prefix.IsValid() {
+		return prefix, false
+	}
+	if !prefix.Addr().Is4In6() {
+		return prefix, true
+	}
+	if prefix.Bits() < 96 {
+		return prefix, false
+	}
+	return netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96), true
+}
+
+// Add stores value for prefix, host bits are masked so 10.1.2.3/8 is stored as 10.0.0.0/8
+// and IPv4-mapped IPv6 prefixes are stored as IPv4 so ::ffff:10.0.0.0/104 is stored as 10.0.0.0/8
+func (t *IPTable) Add(prefix netip.Prefix, value string) bool {
+	prefix, ok := unmap(prefix)
+	if !ok {
+		return false
+	}
+	return t.treeFor(prefix.Addr()).AddBits(prefix.Addr().AsSlice(), prefix.Bits(), value)
+}
+
+// Get returns the value stored for exactly prefix
+func (t *IPTable) Get(prefix netip.Prefix) (string, bool) {
+	prefix, ok := unmap(prefix)
+	if !ok {
+		return "", false
+	}
+	node := t.treeFor(prefix.Addr()).SearchBits(prefix.Addr().AsSlice(), prefix.Bits())
+	if node == nil {
+		return "", false
+	}
+	return node.Value, true
+}
+
+func (t *IPTable) Delete(prefix netip.Prefix) bool {
+	prefix, ok := unmap(prefix)
+	if !ok {
+		return false
+	}
+	return t.treeFor(prefix.Addr()).DeleteBits(prefix.Addr().AsSlice(), prefix.Bits())
+}
+
+// Lookup returns the most specific prefix containing addr and its value,
+// IPv4-mapped IPv6 addresses are looked up as IPv4
+func (t *IPTable) Lookup(addr netip.Addr) (netip.Prefix, string, bool) {
+	if !addr.IsValid() {
+		return netip.Prefix{}, "", false
+	}
+	addr = addr.Unmap()
+	bits, value, found := t.treeFor(addr).LongestPrefix(addr.AsSlice(), addr.BitLen())
+	if !found {
+		return netip.Prefix{}, "", false
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix, value, true
+}
+
+// Walk calls fn for every stored prefix, IPv4 first, until fn returns false
+func (t *IPTable) Walk(fn func(prefix netip.Prefix, value string) bool) {
+	proceed := true
+	walk := func(size int) func(key []byte, bits int, value string) bool {
+		return func(key []byte, bits int, value string) bool {
+			addrBytes := make([]byte, size)
+			copy(addrBytes, key)
+			addr, _ := netip.AddrFromSlice(addrBytes)
+			proceed = fn(netip.PrefixFrom(addr, bits), value)
+			return proceed
+		}
+	}
+	t.ipv4.Walk(walk(4))
+	if proceed {
+		t.ipv6.Walk(walk(16))
+	}
+}
@@ -0,0 +1,90 @@
+package src
+
+// MultiRTree maps every key to a set of values kept in insertion order,
+// a key stays in the tree until its last value is removed
+type MultiRTree struct {
+	tree   *RTree
+	values map[string][]string
+}
+
+// NewMultiRTree returns an empty MultiRTree
+func NewMultiRTree() *MultiRTree {
+	return &MultiRTree{
+		tree:   NewRTree(),
+		values: map[string][]string{},
+	}
+}
+
+// Len returns the number of keys
+func (m *MultiRTree) Len() int {
+	return len(m.values)
+}
+
+// Append adds value to key, returns false if the key is invalid or already holds value
+func (m *MultiRTree) Append(key string, value string) bool {
+	if validateKey(key) != nil {
+		return false
+	}
+
+	values, exists := m.values[key]
+	if !exists {
+		m.tree.Add(key, "")
+	}
+	for _, v := range values {
+		if v == value {
+			return false
+		}
+	}
+	m.values[key] = append(values, value)
+	return true
+}
+
+// Values returns a copy of the values of key
+func (m *MultiRTree) Values(key string) []string {
+	values := m.values[key]
+	result := make([]string, len(values))
+	copy(result, values)
+	return result
+}
+
+// RemoveValue removes value from key and drops the key with its last value
+func (m *MultiRTree) RemoveValue(key string, value string) bool {
+	values := m.values[key]
+	for i, v := range values {
+		if v != value {
+			continue
+		}
+		if len(values) == 1 {
+			return m.Delete(key)
+		}
+		m.values[key] = append(values[:i:i], values[i+1:]...)
+		return true
+	}
+	return false
+}
+
+// Delete removes key with all its values
+func (m *MultiRTree) Delete(key string) bool {
+	if _, exists := m.values[key]; !exists {
+		return false
+	}
+	delete(m.values, key)
+	return m.tree.Delete(key)
+}
+
+// WalkPrefix calls fn with the values of every key starting with prefix until fn returns false
+func (m *MultiRTree) WalkPrefix(prefix string, fn func(key string, values []string) bool) {
+	m.tree.WalkPrefix(prefix, func(key string, _ string) bool {
+		return fn(key, m.Values(key))
+	})
+}
+
+// PrefixValues returns the values of every key starting with prefix, in key order
+func (m *MultiRTree) PrefixValues(prefix string) []string {
+	result := []string{}
+	m.tree.WalkPrefix(prefix, func(key string, _ string) bool {
+		result = append(result, m.values[key]...)
+		return true
+	})
+	return result
+}
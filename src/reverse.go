@@ -0,0 +1,123 @@
+package src
+
+import "strings"
+
+// ReverseMode selects how a ReverseRTree turns natural keys into stored keys
+type ReverseMode int
+
+const (
+	// ReverseBytes stores keys with their bytes in reverse order, "api.example.com" becomes "moc.elpmaxe.ipa"
+	ReverseBytes ReverseMode = iota
+	// ReverseLabels stores keys with their labels in reverse order, "api.example.com" becomes "com.example.api"
+	ReverseLabels
+)
+
+// ReverseRTree stores keys reversed so suffix and wildcard queries become prefix walks
+type ReverseRTree struct {
+	tree      *RTree
+	mode      ReverseMode
+	separator string
+}
+
+// NewReverseRTree returns an empty ReverseRTree, separator is used by ReverseLabels and defaults to "."
+func NewReverseRTree(mode ReverseMode, separator string) *ReverseRTree {
+	if separator == "" {
+		separator = "."
+	}
+	return &ReverseRTree{
+		tree:      NewRTree(),
+		mode:      mode,
+		separator: separator,
+	}
+}
+
+// Tree returns the underlying tree holding the stored keys
+func (r *ReverseRTree) Tree() *RTree {
+	return r.tree
+}
+
+// StoredKey translates a natural key to the form kept in the tree
+func (r *ReverseRTree) StoredKey(key string) string {
+	if r.mode == ReverseLabels {
+		labels := strings.Split(key, r.separator)
+		for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+			labels[i], labels[j] = labels[j], labels[i]
+		}
+		return strings.Join(labels, r.separator)
+	}
+	reversed := make([]byte, len(key))
+	for i := 0; i < len(key); i++ {
+		reversed[len(key)-1-i] = key[i]
+	}
+	return string(reversed)
+}
+
+// NaturalKey translates a stored key, as found while walking Tree(), back to its natural form
+func (r *ReverseRTree) NaturalKey(storedKey string) string {
+	// both reversals are involutions
+	return r.StoredKey(storedKey)
+}
+
+func (r *ReverseRTree) Add(key string, value string) bool {
+	return r.tree.Add(r.StoredKey(key), value)
+}
+
+// Search returns the node stored for the natural key
+//
+// Deprecated: Search exposes the internal node, use Get and Contains.
+func (r *ReverseRTree) Search(key string) *Node {
+	return r.tree.Search(r.StoredKey(key))
+}
+
+func (r *ReverseRTree) Get(key string) (string, bool) {
+	return r.tree.Get(r.StoredKey(key))
+}
+
+func (r *ReverseRTree) Contains(key string) bool {
+	return r.tree.Contains(r.StoredKey(key))
+}
+
+func (r *ReverseRTree) Delete(key string) bool {
+	return r.tree.Delete(r.StoredKey(key))
+}
+
+// WalkSuffix calls fn with the natural key of every entry ending with suffix,
+// with ReverseLabels only whole labels match so "example.com" does not match "myexample.com"
+func (r *ReverseRTree) WalkSuffix(suffix string, fn func(key string, value string) bool) {
+	stored := r.StoredKey(suffix)
+	if r.mode == ReverseLabels && suffix != "" {
+		r.tree.WalkPrefix(stored, func(key string, value string) bool {
+			if key != stored && !strings.HasPrefix(key, stored+r.separator) {
+				return true
+			}
+			return fn(r.NaturalKey(key), value)
+		})
+		return
+	}
+	r.tree.WalkPrefix(stored, func(key string, value string) bool {
+		return fn(r.NaturalKey(key), value)
+	})
+}
+
+// WalkWildcard calls fn with the natural key of every entry matching pattern,
+// a leading "*" matches any non empty head so "*.example.com" matches "api.example.com" but not "example.com",
+// a pattern without wildcard matches only itself
+func (r *ReverseRTree) WalkWildcard(pattern string, fn func(key string, value string) bool) {
+	if !strings.HasPrefix(pattern, "*") {
+		if value, exists := r.Get(pattern); exists {
+			fn(pattern, value)
+		}
+		return
+	}
+
+	suffix := pattern[1:]
+	if r.mode == ReverseLabels {
+		suffix = strings.TrimPrefix(suffix, r.separator)
+	}
+	r.WalkSuffix(suffix, func(key string, value string) bool {
+		if len(key) == len(suffix) {
+			return true
+		}
+		return fn(key, value)
+	})
+}
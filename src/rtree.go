@@ -1,22 +1,40 @@
 package src
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 )
 
 const ROOT = "ROOT"
 
+var (
+	ErrEmptyKey    = errors.New("rtree: empty key")
+	ErrReservedKey = errors.New("rtree: reserved key " + ROOT)
+	// ErrVetoed is returned when a BeforeAdd hook refused the write
+	ErrVetoed = errors.New("rtree: write vetoed by a BeforeAdd hook")
+)
+
 type Node struct {
-	Key        string
-	Value      string
-	Children   map[string]*Node
-	IsEnd      bool
-	parentNode *Node
+	Key   string
+	Value string
+	IsEnd bool
+	// children is nil on a leaf
+	children childIndex
 }
 
 type RTree struct {
 	Root *Node
+	mu   sync.RWMutex
+	// uncompacted is set once Delete leaves a non-terminal node with less than two children
+	uncompacted bool
+	// watches is created by the first Watch call
+	watches *watchRegistry
+	hooks   mutationHooks
+	// expiries holds the deadline of the keys stored by AddWithTTL
+	expiries map[string]time.Time
 }
 
 func PrintNode(node *Node, printChildren bool) {
@@ -24,278 +42,471 @@ func PrintNode(node *Node, printChildren bool) {
 	fmt.Println("key", node.Key)
 	fmt.Println("value", node.Value)
 	fmt.Println("isEnd", node.IsEnd)
-	fmt.Println("parentNode", node.parentNode)
-	fmt.Println("children len", len(node.Children))
+	fmt.Println("children len", node.childCount())
 	if printChildren {
-		for _, n := range node.Children {
+		node.eachChild(func(n *Node) bool {
 			PrintNode(n, printChildren)
-		}
+			return true
+		})
 	}
 }
 
 func NewNode(key string, value string) *Node {
 	return &Node{
-		Key:        key,
-		Value:      value,
-		Children:   map[string]*Node{},
-		IsEnd:      true,
-		parentNode: nil,
+		Key:   key,
+		Value: value,
+		IsEnd: true,
 	}
 }
 
 func NewRTree() *RTree {
 	return &RTree{
 		Root: &Node{
-			Key:      ROOT,
-			Children: map[string]*Node{},
-			IsEnd:    false,
+			Key:   ROOT,
+			IsEnd: false,
 		},
 	}
 }
 
+// AddNodesToChildren adds nodes as children of parentNode and skips nodes with an empty edge.
+// A node whose edge shares a first byte with an existing child is merged into it: the edges are
+// split at their common prefix, so adding "test2" next to "test1" leaves a "test" child with
+// "1" and "2" below it. Merged nodes have their edge trimmed and an edge equal to an existing
+// one overwrites its value and merges its children
 func (r *RTree) AddNodesToChildren(parentNode *Node, nodes ...*Node) *Node {
-	parentNode.Children = r.appendNodesToMap(parentNode.Children, nodes...)
+	for _, node := range nodes {
+		if node.Key != "" {
+			graftChild(parentNode, node)
+		}
+	}
 	return parentNode
 }
 
+// graftChild adds node under parentNode, splitting the child whose edge shares its first byte
+func graftChild(parentNode *Node, node *Node) {
+	existing := parentNode.child(node.Key[0])
+	if existing == nil {
+		parentNode.setChild(node)
+		return
+	}
+
+	shared := commonPrefixLength(existing.Key, node.Key)
+	if shared < len(existing.Key) {
+		middle := &Node{Key: existing.Key[:shared]}
+		existing.Key = existing.Key[shared:]
+		middle.setChild(existing)
+		parentNode.setChild(middle)
+		existing = middle
+	}
+
+	if shared < len(node.Key) {
+		node.Key = node.Key[shared:]
+		graftChild(existing, node)
+		return
+	}
+	if node.IsEnd {
+		existing.Value, existing.IsEnd = node.Value, true
+	}
+	for _, child := range node.Children() {
+		graftChild(existing, child)
+	}
+}
+
 func (r *RTree) AddChildrenToNodeChildren(parentNode *Node, nodesToAdd ...map[string]*Node) *Node {
-	for _, v := range nodesToAdd {
-		parentNode.Children = r.appendChildrenMapToMap(parentNode.Children, v)
+	for _, nodes := range nodesToAdd {
+		for _, node := range nodes {
+			r.AddNodesToChildren(parentNode, node)
+		}
 	}
 	return parentNode
 }
 
+// DeleteNodeFromChildren removes the child of parentNode whose edge is key
 func (r *RTree) DeleteNodeFromChildren(parentNode *Node, key string) *Node {
-	delete(parentNode.Children, key)
+	if _, exists := parentNode.Child(key); exists {
+		parentNode.removeChild(key[0])
+	}
 	return parentNode
 }
 
-func (tree *RTree) Add(key string, value string) bool {
-	return tree.addHandler(key, value, tree.Root)
+func validateKey(key string) error {
+	if key == "" {
+		return ErrEmptyKey
+	}
+	if key == ROOT {
+		return ErrReservedKey
+	}
+	return nil
 }
 
-func (r *RTree) addHandler(key string, value string, node *Node) bool {
-	result := false
-
-	if key == node.Key {
-		return false
-	}
+func (tree *RTree) Add(key string, value string) bool {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	_, stored := tree.put(key, func(string, bool) (string, bool) {
+		return value, true
+	})
+	return stored
+}
 
-	// Add when is empty
-	if len(node.Children) == 0 {
-		newNode := NewNode(key, value)
-		r.AddNodesToChildren(node, newNode)
-		return true
+// Update stores the value returned by fn in a single descent, fn receives the current value
+// and can return false to leave the tree untouched, returns the value of key after the update.
+// fn runs with the tree locked and must not call the tree
+func (tree *RTree) Update(key string, fn func(old string, exists bool) (string, bool)) (string, bool) {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	event, stored := tree.put(key, fn)
+	if stored {
+		return event.NewValue, true
 	}
+	return event.OldValue, event.Existed
+}
 
-	// Is not empty check the key
-	tmpKey := ""
-	tmpKeyAlreadyPresent := false
-	tmpKeyOffset := ""
-	tmpKeyOrphan := ""
-	childKey := ""
-	var selectedNode *Node
-	for k, n := range node.Children {
-		selectedNode = n
-		childKey = k
-		tmpKey = ""
-		tmpKeyOffset = ""
-		tmpKeyOrphan = ""
-		if len(n.Key) == 0 || len(key) == 0 || n.Key[0] != key[0] {
-			selectedNode = nil
-			childKey = ""
-			continue
-		}
-		for j := 0; j < len(n.Key) && j < len(key); j++ {
-			if key[j] == n.Key[j] {
-				tmpKey = key[:j+1]
-			} else {
-				break
-			}
-		}
-		if tmpKey == n.Key {
-			tmpKeyAlreadyPresent = true
+// put runs addHandler from the root with the BeforeAdd hooks applied to the value returned by update,
+// the returned event holds the value found and the value stored, tree.mu must be held
+func (tree *RTree) put(key string, update func(old string, exists bool) (string, bool)) (Event, bool) {
+	tree.dropIfExpired(key)
+	event := Event{Type: EventPut, Key: key}
+	stored := tree.addHandler(key, func(old string, exists bool) (string, bool) {
+		event.OldValue, event.Existed = old, exists
+		value, store := update(old, exists)
+		if store {
+			value, store = tree.hooks.beforeAdd(key, value)
 		}
-		if len(tmpKey) < len(key) {
-			tmpKeyOffset = key[len(tmpKey):]
-		}
-		if len(tmpKey) < len(n.Key) {
-			tmpKeyOrphan = n.Key[len(tmpKey):]
-		}
-		break
+		event.NewValue = value
+		return value, store
+	}, tree.Root)
+	if stored {
+		tree.changed(event)
 	}
+	return event, stored
+}
 
-	if tmpKeyAlreadyPresent && tmpKeyOffset == "" {
-		currentNode := node.Children[childKey]
-		currentNode.IsEnd = true
-		currentNode.Value = value
-		return true
+// Put stores value for key, returns the previous value and whether it was replaced,
+// ErrVetoed when a BeforeAdd hook refused the write
+func (tree *RTree) Put(key string, value string) (string, bool, error) {
+	if err := validateKey(key); err != nil {
+		return "", false, err
 	}
-	if selectedNode != nil && tmpKeyAlreadyPresent && tmpKeyOffset != "" {
-		return r.addHandler(tmpKeyOffset, value, selectedNode)
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	event, stored := tree.put(key, func(string, bool) (string, bool) {
+		return value, true
+	})
+	if !stored {
+		return event.OldValue, false, ErrVetoed
 	}
-	if tmpKeyOrphan == "" && tmpKey != "" && tmpKeyOffset != "" {
-		return r.addHandler(tmpKeyOffset, value, selectedNode)
-	} else if tmpKeyOrphan == "" && tmpKey == "" && tmpKeyOffset == "" {
-		newNode := NewNode(key, value)
-		r.AddNodesToChildren(node, newNode)
-		return true
-	} else if tmpKeyOrphan != "" && tmpKey != "" && tmpKeyOffset != "" {
-		currentNode := node.Children[childKey]
+	return event.OldValue, event.Existed, nil
+}
 
-		originalIsEnd := currentNode.IsEnd
-		originalValue := currentNode.Value
+// Insert stores value only when key is missing, returns whether it was stored,
+// ErrVetoed when a BeforeAdd hook refused the write
+func (tree *RTree) Insert(key string, value string) (bool, error) {
+	if err := validateKey(key); err != nil {
+		return false, err
+	}
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	event, stored := tree.put(key, func(_ string, exists bool) (string, bool) {
+		return value, !exists
+	})
+	if !stored && !event.Existed {
+		return false, ErrVetoed
+	}
+	return stored, nil
+}
 
-		currentNode.Key = tmpKey
-		currentNode.IsEnd = false
-		currentNode.Value = ""
+// CompareAndSwap stores newValue only when key holds oldValue
+func (tree *RTree) CompareAndSwap(key string, oldValue string, newValue string) bool {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	_, swapped := tree.put(key, func(current string, exists bool) (string, bool) {
+		return newValue, exists && current == oldValue
+	})
+	return swapped
+}
 
-		delete(node.Children, childKey)
-		node.Children[tmpKey] = currentNode
+// LoadOrStore returns the value of key if present, otherwise stores value, loaded reports whether key was present.
+// The store fails with ErrVetoed when a BeforeAdd hook refused it
+func (tree *RTree) LoadOrStore(key string, value string) (string, bool, error) {
+	if err := validateKey(key); err != nil {
+		return "", false, err
+	}
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	event, stored := tree.put(key, func(_ string, exists bool) (string, bool) {
+		return value, !exists
+	})
+	if stored {
+		return event.NewValue, false, nil
+	}
+	if !event.Existed {
+		return "", false, ErrVetoed
+	}
+	return event.OldValue, true, nil
+}
 
-		orphanNode := NewNode(tmpKeyOrphan, "")
-		if originalIsEnd {
-			orphanNode.IsEnd = true
-			orphanNode.Value = originalValue
+// GetOrInsert returns the value of key if present, otherwise stores the value returned by create,
+// inserted reports whether the created value was stored. create runs with the tree locked and must not call the tree
+func (tree *RTree) GetOrInsert(key string, create func() string) (string, bool) {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	event, inserted := tree.put(key, func(current string, exists bool) (string, bool) {
+		if exists {
+			return current, false
 		}
+		return create(), true
+	})
+	if inserted {
+		return event.NewValue, true
+	}
+	return event.OldValue, false
+}
 
-		r.AddChildrenToNodeChildren(orphanNode, currentNode.Children)
-		currentNode.Children = map[string]*Node{}
-		r.AddNodesToChildren(currentNode, orphanNode)
-		newNode := NewNode(tmpKeyOffset, value)
-		r.AddNodesToChildren(currentNode, newNode)
-
-		return true
-	} else if tmpKeyOrphan != "" && tmpKey != "" && tmpKeyOffset == "" {
-		currentNode := node.Children[childKey]
-		currentNode.Key = tmpKey
+// addHandler descends from node along key, the child to follow is the one indexed under the next byte
+func (r *RTree) addHandler(key string, update func(old string, exists bool) (string, bool), node *Node) bool {
+	if node == r.Root && validateKey(key) != nil {
+		return false
+	}
 
-		orphanNode := NewNode(tmpKeyOrphan, currentNode.Value)
-		if currentNode.IsEnd {
-			orphanNode.IsEnd = true
+	for {
+		child := node.child(key[0])
+		if child == nil {
+			value, ok := update("", false)
+			if !ok {
+				return false
+			}
+			node.setChild(NewNode(key, value))
+			return true
 		}
 
-		currentNode.IsEnd = true
-		currentNode.Value = value
-
-		delete(node.Children, childKey)
-		node.Children[tmpKey] = currentNode
-
-		r.AddChildrenToNodeChildren(orphanNode, currentNode.Children)
-		currentNode.Children = map[string]*Node{}
-		r.AddNodesToChildren(currentNode, orphanNode)
+		shared := commonPrefixLength(key, child.Key)
+		if shared == len(child.Key) {
+			key = key[shared:]
+			if key == "" {
+				value, ok := update(child.Value, child.IsEnd)
+				if !ok {
+					return false
+				}
+				child.IsEnd = true
+				child.Value = value
+				return true
+			}
+			node = child
+			continue
+		}
 
-		return true
-	} else if tmpKeyOffset != "" && tmpKeyOrphan == "" {
-		newNode := NewNode(tmpKeyOffset, value)
-		r.AddNodesToChildren(node.Children[childKey], newNode)
+		value, ok := update("", false)
+		if !ok {
+			return false
+		}
+		// Split the edge of child, it keeps the rest of its edge with its value and children
+		middleNode := NewNode(key[:shared], "")
+		middleNode.IsEnd = false
+		child.Key = child.Key[shared:]
+		middleNode.setChild(child)
+		if shared == len(key) {
+			middleNode.IsEnd = true
+			middleNode.Value = value
+		} else {
+			middleNode.setChild(NewNode(key[shared:], value))
+		}
+		node.setChild(middleNode)
 		return true
 	}
-
-	return result
 }
 
+// Search returns the node stored for key
+//
+// Deprecated: Search exposes the internal node, use Get and Contains to read values or Seek for a read-only Cursor.
 func (tree *RTree) Search(key string) *Node {
-	return tree.searchHandler(key, "", tree.Root, nil, 0)
+	node, _ := tree.lookup(key)
+	return node
 }
 
-func (r *RTree) searchHandler(key string, foundedKeyPart string, node *Node, parentNode *Node, level int) *Node {
-
-	search := true
-	for search {
-		search = false
-
-		keyToCheck := fmt.Sprintf("%s%s", foundedKeyPart, key)
-		nod, exists := node.Children[keyToCheck]
-		if exists && nod.IsEnd {
-			nod.parentNode = node
-			return nod
-		} else {
-
-			for k, child := range node.Children {
-
-				nodeKey := fmt.Sprintf("%s%s", foundedKeyPart, k)
-				if keyToCheck == nodeKey && child.IsEnd {
-					child.parentNode = node
-					return child
-				}
+func (tree *RTree) Get(key string) (string, bool) {
+	node, value := tree.lookup(key)
+	return value, node != nil
+}
 
-				tmpFoundedKeyParts := ""
-				tmpKey := keyToCheck
+// lookup returns the node ending key and its value read under the lock,
+// an expired key is removed and reported missing
+func (tree *RTree) lookup(key string) (*Node, string) {
+	tree.mu.RLock()
+	node, _ := tree.searchHandler(key, tree.Root)
+	if node == nil {
+		tree.mu.RUnlock()
+		return nil, ""
+	}
+	if tree.isExpired(key) {
+		tree.mu.RUnlock()
+		tree.mu.Lock()
+		tree.dropIfExpired(key)
+		tree.mu.Unlock()
+		return nil, ""
+	}
+	value := node.Value
+	tree.mu.RUnlock()
+	return node, value
+}
 
-				if strings.HasPrefix(tmpKey, nodeKey) {
-					tmpFoundedKeyParts = nodeKey
-					tmpKey = keyToCheck[len(nodeKey):]
-				} else {
-					continue
-				}
+func (tree *RTree) Contains(key string) bool {
+	_, exists := tree.Get(key)
+	return exists
+}
 
-				key = tmpKey
-				foundedKeyPart = tmpFoundedKeyParts
-				search = true
-				parentNode = node
-				node = child
-				level = level + 1
-				break
+// searchHandler returns the node ending key and its parent, it compares slices of key against the edges
+// in place so a lookup allocates nothing
+func (r *RTree) searchHandler(key string, node *Node) (*Node, *Node) {
+	for key != "" {
+		next := node.child(key[0])
+		if next == nil || !strings.HasPrefix(key, next.Key) {
+			return nil, nil
+		}
+		key = key[len(next.Key):]
+		if key == "" {
+			if next.IsEnd {
+				return next, node
 			}
-
+			return nil, nil
 		}
+		node = next
+	}
+	return nil, nil
+}
 
+// walkBatchSize is the number of matches WalkPrefix reads under the lock before calling fn
+const walkBatchSize = 256
+
+// WalkPrefix calls fn for every key starting with prefix in key order until fn returns false.
+// Matches are read in batches under the lock and fn runs with the tree unlocked, so fn may call
+// the tree, a key written during the walk is visited only if it sorts after the last key visited
+func (tree *RTree) WalkPrefix(prefix string, fn func(key string, value string) bool) {
+	after := ""
+	for {
+		batch := make([]Entry, 0, walkBatchSize)
+		tree.mu.RLock()
+		tree.walkPrefixHandler(prefix, "", after, tree.Root, func(key string, value string) bool {
+			batch = append(batch, Entry{Key: key, Value: value})
+			return len(batch) < walkBatchSize
+		})
+		tree.mu.RUnlock()
+
+		for _, entry := range batch {
+			if !fn(entry.Key, entry.Value) {
+				return
+			}
+		}
+		if len(batch) < walkBatchSize {
+			return
+		}
+		after = batch[len(batch)-1].Key
 	}
-	return nil
 }
 
-func (tree *RTree) Delete(key string) bool {
-	node := tree.Search(key)
-	if node != nil && node.parentNode != nil && node.IsEnd && len(node.Children) == 0 {
-		tree.DeleteNodeFromChildren(node.parentNode, node.Key)
-		return true
-	} else if node != nil && node.IsEnd && len(node.Children) > 0 {
-		node.IsEnd = false
-		// compactHandler(node)
+// walkPrefixHandler calls fn for every key starting with prefix that sorts after the key after
+func (r *RTree) walkPrefixHandler(prefix string, path string, after string, node *Node, fn func(key string, value string) bool) bool {
+	if prefix == "" {
+		return r.walkHandler(path, after, node, fn)
+	}
+	child := node.child(prefix[0])
+	if child == nil {
 		return true
 	}
-	return false
+	if strings.HasPrefix(prefix, child.Key) {
+		return r.walkPrefixHandler(prefix[len(child.Key):], path+child.Key, after, child, fn)
+	}
+	if strings.HasPrefix(child.Key, prefix) {
+		return r.walkHandler(path+child.Key, after, child, fn)
+	}
+	return true
 }
 
-func (tree *RTree) Compact() {
-	root := tree.Root
-	for _, n := range root.Children {
-		tree.compactHandler(n)
+func (r *RTree) walkHandler(path string, after string, node *Node, fn func(key string, value string) bool) bool {
+	if node.IsEnd && node != r.Root && path > after && !r.isExpired(path) {
+		if !fn(path, node.Value) {
+			return false
+		}
 	}
-
+	return node.eachChild(func(child *Node) bool {
+		childPath := path + child.Key
+		// Every key below child sorts before after, skip the subtree
+		if childPath < after && !strings.HasPrefix(after, childPath) {
+			return true
+		}
+		return r.walkHandler(childPath, after, child, fn)
+	})
 }
 
-func (r *RTree) compactHandler(node *Node) {
-	if len(node.Children) == 1 && !node.IsEnd {
-		var child *Node
-		for _, value := range node.Children {
-			child = value
+func (tree *RTree) LongestPrefix(key string) (string, string, bool) {
+	tree.mu.RLock()
+	defer tree.mu.RUnlock()
+	matchedKey, matchedValue, found := "", "", false
+	node := tree.Root
+	offset := 0
+	for offset < len(key) {
+		next := node.child(key[offset])
+		if next == nil || !strings.HasPrefix(key[offset:], next.Key) {
+			break
+		}
+		offset += len(next.Key)
+		if next.IsEnd && !tree.isExpired(key[:offset]) {
+			matchedKey, matchedValue, found = key[:offset], next.Value, true
 		}
-		node.Key = fmt.Sprintf("%s%s", node.Key, child.Key)
-		node.IsEnd = child.IsEnd
-		node.Value = child.Value
-		node.Children = child.Children
+		node = next
 	}
+	return matchedKey, matchedValue, found
 }
 
-func (r *RTree) appendToMap(m1 map[string]*Node, m2 map[string]*Node) map[string]*Node {
-	for key, value := range m2 {
-		m1[key] = value
+func (tree *RTree) Delete(key string) bool {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	if tree.dropIfExpired(key) {
+		return false
 	}
-	return m1
+	return tree.deleteHandler(key, false)
 }
 
-func (r *RTree) appendChildrenMapToMap(m1 map[string]*Node, nodesToAdd ...map[string]*Node) map[string]*Node {
-	for _, mapToAdd := range nodesToAdd {
-		r.appendToMap(m1, mapToAdd)
+// deleteHandler removes key, an expired key skips the BeforeDelete hooks, tree.mu must be held
+func (tree *RTree) deleteHandler(key string, expired bool) bool {
+	node, parentNode := tree.searchHandler(key, tree.Root)
+	if node == nil || !node.IsEnd || (!expired && !tree.hooks.beforeDelete(key, node.Value)) {
+		return false
+	}
+	event := Event{Type: EventDelete, Key: key, OldValue: node.Value, Existed: true, Expired: expired}
+	if parentNode != nil && node.childCount() == 0 {
+		parentNode.removeChild(node.Key[0])
+		if parentNode != tree.Root && !parentNode.IsEnd && parentNode.childCount() < 2 {
+			tree.uncompacted = true
+		}
+	} else if node.childCount() > 0 {
+		node.IsEnd = false
+		node.Value = ""
+		if node.childCount() < 2 {
+			tree.uncompacted = true
+		}
+		// compactHandler(node)
+	} else {
+		return false
 	}
-	return m1
+	tree.changed(event)
+	return true
 }
 
-func (r *RTree) appendNodesToMap(m1 map[string]*Node, nodes ...*Node) map[string]*Node {
-	for _, node := range nodes {
-		m1[node.Key] = node
+// Compact removes the nodes left without a value by Delete and merges every non-terminal node
+// having a single child into it
+func (tree *RTree) Compact() {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	tree.compactHandler(tree.Root)
+	tree.uncompacted = false
+}
+
+func (r *RTree) compactHandler(node *Node) {
+	// Children are collected first as pruning changes the index being walked
+	for _, child := range node.Children() {
+		r.compactHandler(child)
+		if !child.IsEnd {
+			pruneChild(node, child)
+		}
 	}
-	return m1
 }
@@ -0,0 +1,105 @@
+package src
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// snapshotMagic starts every snapshot file, it is followed by the little endian uint64 entry count
+// and one put record per entry framed as in the write-ahead log
+const snapshotMagic = "RTSNAP1\n"
+
+var ErrCorruptSnapshot = errors.New("rtree: corrupt snapshot")
+
+// writeSnapshot writes entries to a temporary file and renames it over path once synced
+func writeSnapshot(path string, entries []walRecord) error {
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	writer := bufio.NewWriter(file)
+	header := make([]byte, len(snapshotMagic)+8)
+	copy(header, snapshotMagic)
+	binary.LittleEndian.PutUint64(header[len(snapshotMagic):], uint64(len(entries)))
+	writer.Write(header)
+	for _, entry := range entries {
+		writer.Write(encodeWALRecord(entry))
+	}
+
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// loadSnapshot calls fn for every entry of the snapshot at path, a missing snapshot is empty
+func loadSnapshot(path string, fn func(record walRecord) error) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header := make([]byte, len(snapshotMagic)+8)
+	if _, err := io.ReadFull(file, header); err != nil || !bytes.Equal(header[:len(snapshotMagic)], []byte(snapshotMagic)) {
+		return ErrCorruptSnapshot
+	}
+	expected := binary.LittleEndian.Uint64(header[len(snapshotMagic):])
+
+	count := uint64(0)
+	valid, err := readWALRecords(file, func(record walRecord) error {
+		if record.op != walPut {
+			return ErrCorruptSnapshot
+		}
+		count++
+		return fn(record)
+	})
+	if errors.Is(err, errWALCorrupt) {
+		return ErrCorruptSnapshot
+	}
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if count != expected || valid != info.Size()-int64(len(header)) {
+		return ErrCorruptSnapshot
+	}
+	return nil
+}
+
+// syncDir makes a rename in dir durable
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// Some platforms do not support syncing directories
+	file.Sync()
+	return nil
+}
@@ -0,0 +1,104 @@
+package src
+
+import "sort"
+
+// suffixMarker keeps stored suffixes away from the reserved ROOT key
+const suffixMarker = "$"
+
+// SubstringIndex answers substring and suffix queries by storing every suffix of a key in a radix tree
+type SubstringIndex struct {
+	// suffixes holds every suffix behind suffixMarker with an empty value, the suffix is read back from the key
+	suffixes *RTree
+	// owners maps a suffix to the keys ending with it, the suffixes share the memory of the keys
+	owners map[string]map[string]struct{}
+	keys   map[string]struct{}
+}
+
+// NewSubstringIndex returns an empty SubstringIndex
+func NewSubstringIndex() *SubstringIndex {
+	return &SubstringIndex{
+		suffixes: NewRTree(),
+		owners:   map[string]map[string]struct{}{},
+		keys:     map[string]struct{}{},
+	}
+}
+
+// Add indexes all the suffixes of key, returns false if key is empty or already indexed
+func (s *SubstringIndex) Add(key string) bool {
+	if key == "" {
+		return false
+	}
+	if _, exists := s.keys[key]; exists {
+		return false
+	}
+	s.keys[key] = struct{}{}
+
+	for i := 0; i < len(key); i++ {
+		suffix := key[i:]
+		owners, exists := s.owners[suffix]
+		if !exists {
+			owners = map[string]struct{}{}
+			s.owners[suffix] = owners
+			s.suffixes.Add(suffixMarker+suffix, "")
+		}
+		owners[key] = struct{}{}
+	}
+	return true
+}
+
+// Delete removes key and the suffixes no other key is sharing
+func (s *SubstringIndex) Delete(key string) bool {
+	if _, exists := s.keys[key]; !exists {
+		return false
+	}
+	delete(s.keys, key)
+
+	for i := 0; i < len(key); i++ {
+		suffix := key[i:]
+		owners := s.owners[suffix]
+		delete(owners, key)
+		if len(owners) == 0 {
+			delete(s.owners, suffix)
+			s.suffixes.Delete(suffixMarker + suffix)
+		}
+	}
+	return true
+}
+
+// Len returns the number of indexed keys
+func (s *SubstringIndex) Len() int {
+	return len(s.keys)
+}
+
+// Contains returns the sorted keys containing sub
+func (s *SubstringIndex) Contains(sub string) []string {
+	if sub == "" {
+		return s.sortedKeys(s.keys)
+	}
+
+	found := map[string]struct{}{}
+	s.suffixes.WalkPrefix(suffixMarker+sub, func(stored string, _ string) bool {
+		for key := range s.owners[stored[len(suffixMarker):]] {
+			found[key] = struct{}{}
+		}
+		return true
+	})
+	return s.sortedKeys(found)
+}
+
+// HasSuffix returns the sorted keys ending with suffix
+func (s *SubstringIndex) HasSuffix(suffix string) []string {
+	if suffix == "" {
+		return s.sortedKeys(s.keys)
+	}
+	return s.sortedKeys(s.owners[suffix])
+}
+
+func (s *SubstringIndex) sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,184 @@
+package src
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	TopicSeparator      = "/"
+	SingleLevelWildcard = "+"
+	MultiLevelWildcard  = "#"
+)
+
+// TopicTree matches published topics against MQTT style subscription filters. Filters are the keys
+// of a radix tree of Node, each prefixed with the separator so every level, wildcards included,
+// starts right after a "/" and filters sharing their first levels share nodes
+type TopicTree struct {
+	Root        *Node
+	filters     *RTree
+	subscribers map[string]map[string]struct{}
+}
+
+// NewTopicTree returns an empty TopicTree
+func NewTopicTree() *TopicTree {
+	filters := NewRTree()
+	return &TopicTree{
+		Root:        filters.Root,
+		filters:     filters,
+		subscribers: map[string]map[string]struct{}{},
+	}
+}
+
+// ValidFilter reports whether filter is a valid subscription filter,
+// "+" and "#" must fill a whole level and "#" must be the last level
+func ValidFilter(filter string) bool {
+	if filter == "" {
+		return false
+	}
+	levels := strings.Split(filter, TopicSeparator)
+	for i, level := range levels {
+		if strings.Contains(level, MultiLevelWildcard) && (level != MultiLevelWildcard || i != len(levels)-1) {
+			return false
+		}
+		if strings.Contains(level, SingleLevelWildcard) && level != SingleLevelWildcard {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidTopic reports whether topic can be published, topics can not contain wildcards
+func ValidTopic(topic string) bool {
+	return topic != "" && !strings.ContainsAny(topic, SingleLevelWildcard+MultiLevelWildcard)
+}
+
+// Subscribe registers subscriber for filter, returns false if filter is invalid or subscriber is already registered
+func (t *TopicTree) Subscribe(filter string, subscriber string) bool {
+	if !ValidFilter(filter) {
+		return false
+	}
+
+	subscribers, exists := t.subscribers[filter]
+	if !exists {
+		subscribers = map[string]struct{}{}
+		t.subscribers[filter] = subscribers
+		t.filters.Add(TopicSeparator+filter, "")
+	}
+	if _, exists := subscribers[subscriber]; exists {
+		return false
+	}
+	subscribers[subscriber] = struct{}{}
+	return true
+}
+
+// Unsubscribe removes subscriber from filter and prunes the nodes left without subscribers
+func (t *TopicTree) Unsubscribe(filter string, subscriber string) bool {
+	subscribers := t.subscribers[filter]
+	if _, exists := subscribers[subscriber]; !exists {
+		return false
+	}
+	delete(subscribers, subscriber)
+	if len(subscribers) > 0 {
+		return true
+	}
+
+	delete(t.subscribers, filter)
+	// DeleteBatch also prunes the nodes the filter leaves behind
+	t.filters.DeleteBatch([]string{TopicSeparator + filter})
+	return true
+}
+
+// Subscribers returns the sorted subscribers registered for exactly filter
+func (t *TopicTree) Subscribers(filter string) []string {
+	return t.sortedSubscribers(map[string]struct{}{filter: {}})
+}
+
+// Match returns the sorted subscribers whose filters match topic,
+// wildcards at the first level do not match topics starting with "$"
+func (t *TopicTree) Match(topic string) []string {
+	if !ValidTopic(topic) {
+		return []string{}
+	}
+
+	matched := map[string]struct{}{}
+	t.filters.mu.RLock()
+	t.matchChildren(t.Root, "", TopicSeparator+topic, false, strings.HasPrefix(topic, "$"), matched)
+	t.filters.mu.RUnlock()
+	return t.sortedSubscribers(matched)
+}
+
+// matchHandler walks the edge of node byte by byte against the rest of the topic, path holds
+// the key down to node included. parentOnly is set once the topic ended on a "/" of the filter,
+// only a following "#" can then match as it also covers the parent level
+func (t *TopicTree) matchHandler(node *Node, path string, topic string, parentOnly bool, system bool, matched map[string]struct{}) {
+	start := len(path) - len(node.Key)
+	for offset := 0; offset < len(node.Key); offset++ {
+		c := node.Key[offset]
+		firstLevel := start+offset == 1
+		switch {
+		case c == MultiLevelWildcard[0]:
+			// A valid filter ends with its only "#"
+			if node.IsEnd && !(system && firstLevel) {
+				matched[path[1:]] = struct{}{}
+			}
+			return
+		case parentOnly:
+			return
+		case c == SingleLevelWildcard[0]:
+			if system && firstLevel {
+				return
+			}
+			end := strings.Index(topic, TopicSeparator)
+			if end < 0 {
+				end = len(topic)
+			}
+			topic = topic[end:]
+		case topic == "":
+			if c != TopicSeparator[0] {
+				return
+			}
+			parentOnly = true
+		case c == topic[0]:
+			topic = topic[1:]
+		default:
+			return
+		}
+	}
+	if topic == "" && !parentOnly && node.IsEnd {
+		matched[path[1:]] = struct{}{}
+	}
+	t.matchChildren(node, path, topic, parentOnly, system, matched)
+}
+
+// matchChildren follows the children of node that can match the next topic byte
+func (t *TopicTree) matchChildren(node *Node, path string, topic string, parentOnly bool, system bool, matched map[string]struct{}) {
+	next := []byte{MultiLevelWildcard[0]}
+	switch {
+	case parentOnly:
+	case topic == "":
+		next = append(next, TopicSeparator[0])
+	default:
+		next = append(next, SingleLevelWildcard[0], topic[0])
+	}
+	for _, b := range next {
+		if child := node.child(b); child != nil {
+			t.matchHandler(child, path+child.Key, topic, parentOnly, system, matched)
+		}
+	}
+}
+
+func (t *TopicTree) sortedSubscribers(filters map[string]struct{}) []string {
+	unique := map[string]struct{}{}
+	for filter := range filters {
+		for subscriber := range t.subscribers[filter] {
+			unique[subscriber] = struct{}{}
+		}
+	}
+	subscribers := make([]string, 0, len(unique))
+	for subscriber := range unique {
+		subscribers = append(subscribers, subscriber)
+	}
+	sort.Strings(subscribers)
+	return subscribers
+}
@@ -0,0 +1,140 @@
+package src
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NoTTL is returned by TTL for a key stored without expiry
+const NoTTL time.Duration = -1
+
+// AddWithTTL stores value for key until ttl elapses, the key then reads as missing and is removed
+// by the next access or janitor sweep. A later write without TTL makes the key permanent
+func (tree *RTree) AddWithTTL(key string, value string, ttl time.Duration) bool {
+	if ttl <= 0 {
+		return false
+	}
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	_, stored := tree.put(key, func(string, bool) (string, bool) {
+		return value, true
+	})
+	if !stored {
+		return false
+	}
+	if tree.expiries == nil {
+		tree.expiries = map[string]time.Time{}
+	}
+	tree.expiries[key] = time.Now().Add(ttl)
+	return true
+}
+
+// TTL returns the time left before key expires or NoTTL when it never expires, ok is false when key is missing
+func (tree *RTree) TTL(key string) (time.Duration, bool) {
+	tree.mu.RLock()
+	defer tree.mu.RUnlock()
+	node, _ := tree.searchHandler(key, tree.Root)
+	if node == nil {
+		return 0, false
+	}
+	deadline, exists := tree.expiries[key]
+	if !exists {
+		return NoTTL, true
+	}
+	left := time.Until(deadline)
+	if left <= 0 {
+		return 0, false
+	}
+	return left, true
+}
+
+func (tree *RTree) isExpired(key string) bool {
+	if len(tree.expiries) == 0 {
+		return false
+	}
+	deadline, exists := tree.expiries[key]
+	return exists && !time.Now().Before(deadline)
+}
+
+// hasLiveKey reports whether a key that has not expired ends on node or below it, tree.mu must be held
+func (tree *RTree) hasLiveKey(path string, node *Node) bool {
+	if node.IsEnd && node != tree.Root && !tree.isExpired(path) {
+		return true
+	}
+	return !node.eachChild(func(child *Node) bool {
+		return !tree.hasLiveKey(path+child.Key, child)
+	})
+}
+
+// dropIfExpired deletes key and compacts its path when its deadline passed,
+// returns whether it did, tree.mu must be held
+func (tree *RTree) dropIfExpired(key string) bool {
+	if !tree.isExpired(key) {
+		return false
+	}
+	tree.deleteHandler(key, true)
+	delete(tree.expiries, key)
+	tree.compactPathHandler(tree.Root, key)
+	return true
+}
+
+// RemoveExpired deletes every expired key in key order, returns how many were removed
+func (tree *RTree) RemoveExpired() int {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	now := time.Now()
+	expired := []string{}
+	for key, deadline := range tree.expiries {
+		if !now.Before(deadline) {
+			expired = append(expired, key)
+		}
+	}
+	sort.Strings(expired)
+	for _, key := range expired {
+		tree.dropIfExpired(key)
+	}
+	return len(expired)
+}
+
+// StartJanitor calls RemoveExpired every interval in the background,
+// stop ends the janitor and returns once a running sweep finished
+func (tree *RTree) StartJanitor(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				tree.RemoveExpired()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+		<-exited
+	}
+}
+
+// compactPathHandler prunes the nodes left without a value along the path of rest
+func (r *RTree) compactPathHandler(node *Node, rest string) {
+	if rest == "" {
+		return
+	}
+	child := node.child(rest[0])
+	if child != nil && strings.HasPrefix(rest, child.Key) {
+		r.compactPathHandler(child, rest[len(child.Key):])
+		if !child.IsEnd {
+			pruneChild(node, child)
+		}
+	}
+}
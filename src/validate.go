@@ -0,0 +1,60 @@
+package src
+
+import "fmt"
+
+// ValidationError reports the first node breaking a radix invariant
+type ValidationError struct {
+	// Path holds the edges from the root down to the offending node
+	Path   []string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("rtree: invalid node at %q: %s", e.Path, e.Reason)
+}
+
+// Validate checks the radix invariants and returns a *ValidationError for the first violation
+// in key order: every child is indexed under the first byte of its edge, no edge below root is empty
+// and no node is reachable twice. Unless a Delete happened since the last Compact,
+// non-terminal nodes below root must also have at least two children
+func (tree *RTree) Validate() error {
+	tree.mu.RLock()
+	defer tree.mu.RUnlock()
+	if tree.Root == nil {
+		return &ValidationError{Reason: "missing root"}
+	}
+	if tree.Root.Key != ROOT {
+		return &ValidationError{Reason: fmt.Sprintf("root key %q", tree.Root.Key)}
+	}
+	return tree.validateHandler(tree.Root, nil, map[*Node]struct{}{tree.Root: {}})
+}
+
+func (r *RTree) validateHandler(node *Node, path []string, visited map[*Node]struct{}) error {
+	if node != r.Root && !r.uncompacted && !node.IsEnd && node.childCount() < 2 {
+		return &ValidationError{Path: path, Reason: fmt.Sprintf("non-terminal node with %d children", node.childCount())}
+	}
+	if node.children == nil {
+		return nil
+	}
+
+	var err error
+	node.children.each(func(b byte, child *Node) bool {
+		childPath := append(path[:len(path):len(path)], child.Key)
+		if _, seen := visited[child]; seen {
+			err = &ValidationError{Path: childPath, Reason: "node reachable twice"}
+			return false
+		}
+		visited[child] = struct{}{}
+		if child.Key == "" {
+			err = &ValidationError{Path: childPath, Reason: "empty edge"}
+			return false
+		}
+		if child.Key[0] != b {
+			err = &ValidationError{Path: childPath, Reason: fmt.Sprintf("edge indexed under byte %q", b)}
+			return false
+		}
+		err = r.validateHandler(child, childPath, visited)
+		return err == nil
+	})
+	return err
+}
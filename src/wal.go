@@ -0,0 +1,145 @@
+package src
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+type walOp byte
+
+const (
+	walPut    walOp = 1
+	walDelete walOp = 2
+)
+
+// walHeaderSize is the payload length followed by the payload CRC-32C, both little endian uint32
+const walHeaderSize = 8
+
+// walMaxRecordSize bounds the payload length read back from a damaged header
+const walMaxRecordSize = 1 << 30
+
+var walTable = crc32.MakeTable(crc32.Castagnoli)
+
+var errWALCorrupt = errors.New("rtree: corrupt write-ahead log record")
+
+type walRecord struct {
+	op    walOp
+	key   string
+	value string
+}
+
+// encodeWALRecord frames the record as header, op, uvarint key length, key, uvarint value length, value
+func encodeWALRecord(record walRecord) []byte {
+	payload := make([]byte, 0, 1+2*binary.MaxVarintLen64+len(record.key)+len(record.value))
+	payload = append(payload, byte(record.op))
+	payload = binary.AppendUvarint(payload, uint64(len(record.key)))
+	payload = append(payload, record.key...)
+	payload = binary.AppendUvarint(payload, uint64(len(record.value)))
+	payload = append(payload, record.value...)
+
+	frame := make([]byte, walHeaderSize, walHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.Checksum(payload, walTable))
+	return append(frame, payload...)
+}
+
+func decodeWALPayload(payload []byte) (walRecord, error) {
+	if len(payload) == 0 {
+		return walRecord{}, errWALCorrupt
+	}
+	record := walRecord{op: walOp(payload[0])}
+	if record.op != walPut && record.op != walDelete {
+		return walRecord{}, errWALCorrupt
+	}
+	rest := payload[1:]
+
+	fields := [2]string{}
+	for i := range fields {
+		length, n := binary.Uvarint(rest)
+		if n <= 0 || length > uint64(len(rest)-n) {
+			return walRecord{}, errWALCorrupt
+		}
+		fields[i] = string(rest[n : n+int(length)])
+		rest = rest[n+int(length):]
+	}
+	if len(rest) != 0 {
+		return walRecord{}, errWALCorrupt
+	}
+	record.key, record.value = fields[0], fields[1]
+	return record, nil
+}
+
+// zeroThroughEOF reads reader to the end and reports whether every byte left is zero
+func zeroThroughEOF(reader io.Reader) (bool, error) {
+	chunk := make([]byte, 4096)
+	for {
+		n, err := reader.Read(chunk)
+		for _, b := range chunk[:n] {
+			if b != 0 {
+				return false, nil
+			}
+		}
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// readWALRecords calls fn for every valid record and returns the offset following the last one.
+// A short or damaged record ending the file, or followed only by zero bytes, is the trace of a write
+// torn by a crash and ends the log, a damaged record followed by more data returns errWALCorrupt
+func readWALRecords(reader io.Reader, fn func(record walRecord) error) (int64, error) {
+	buffered := bufio.NewReader(reader)
+	header := make([]byte, walHeaderSize)
+	offset := int64(0)
+	for {
+		if _, err := io.ReadFull(buffered, header); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return offset, nil
+			}
+			return offset, err
+		}
+
+		length := binary.LittleEndian.Uint32(header[0:4])
+		if length > walMaxRecordSize {
+			// Only a header torn at the end of the file can claim more bytes than remain
+			if skipped, err := io.CopyN(io.Discard, buffered, int64(length)); err != nil && err != io.EOF {
+				return offset, err
+			} else if skipped < int64(length) {
+				return offset, nil
+			}
+			return offset, errWALCorrupt
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(buffered, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return offset, nil
+			}
+			return offset, err
+		}
+		record, err := decodeWALPayload(payload)
+		if err == nil && crc32.Checksum(payload, walTable) != binary.LittleEndian.Uint32(header[4:8]) {
+			err = errWALCorrupt
+		}
+		if err != nil {
+			// A filesystem may extend the file before the record lands, leaving zeros through EOF
+			if zeroed, zeroErr := zeroThroughEOF(buffered); zeroErr != nil {
+				return offset, zeroErr
+			} else if zeroed {
+				return offset, nil
+			}
+			return offset, err
+		}
+
+		if err := fn(record); err != nil {
+			return offset, err
+		}
+		offset += int64(walHeaderSize) + int64(length)
+	}
+}
@@ -0,0 +1,183 @@
+package src
+
+import (
+	"strings"
+	"sync"
+)
+
+// watchMarker lets the empty prefix and ROOT be stored in the watcher tree
+const watchMarker = "$"
+
+// EventType tells whether an Event stored or removed a key
+type EventType int
+
+const (
+	EventPut EventType = iota
+	EventDelete
+)
+
+func (t EventType) String() string {
+	if t == EventDelete {
+		return "delete"
+	}
+	return "put"
+}
+
+// Event describes one mutation of a key, Existed reports whether the key held OldValue before it
+// and Expired marks the deletes of keys whose TTL ran out
+type Event struct {
+	Type     EventType
+	Key      string
+	OldValue string
+	NewValue string
+	Existed  bool
+	Expired  bool
+}
+
+// watchRegistry keeps the watched prefixes in a radix tree so a mutation reaches its watchers
+// by walking the mutated key once
+type watchRegistry struct {
+	mu       sync.Mutex
+	prefixes *RTree
+	watchers map[string]map[*watcher]struct{}
+}
+
+type watcher struct {
+	prefix string
+	events chan Event
+	wake   chan struct{}
+	done   chan struct{}
+
+	mu    sync.Mutex
+	queue []Event
+}
+
+func newWatchRegistry() *watchRegistry {
+	return &watchRegistry{
+		prefixes: NewRTree(),
+		watchers: map[string]map[*watcher]struct{}{},
+	}
+}
+
+func (r *watchRegistry) add(w *watcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	watchers, exists := r.watchers[w.prefix]
+	if !exists {
+		watchers = map[*watcher]struct{}{}
+		r.watchers[w.prefix] = watchers
+		r.prefixes.Add(watchMarker+w.prefix, "")
+	}
+	watchers[w] = struct{}{}
+}
+
+func (r *watchRegistry) remove(w *watcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	watchers := r.watchers[w.prefix]
+	delete(watchers, w)
+	if len(watchers) == 0 {
+		delete(r.watchers, w.prefix)
+		r.prefixes.Delete(watchMarker + w.prefix)
+	}
+}
+
+// notify queues event on every watcher whose prefix starts the event key
+func (r *watchRegistry) notify(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.watchers) == 0 {
+		return
+	}
+	r.prefixes.walkPrefixesOf(watchMarker+event.Key, func(prefix string) {
+		for w := range r.watchers[prefix[len(watchMarker):]] {
+			w.push(event)
+		}
+	})
+}
+
+func (w *watcher) push(event Event) {
+	w.mu.Lock()
+	w.queue = append(w.queue, event)
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run hands the queued events to the channel one at a time until the watch is cancelled
+func (w *watcher) run() {
+	defer close(w.events)
+	for {
+		w.mu.Lock()
+		if len(w.queue) == 0 {
+			w.mu.Unlock()
+			select {
+			case <-w.wake:
+				continue
+			case <-w.done:
+				return
+			}
+		}
+		event := w.queue[0]
+		w.queue[0] = Event{}
+		w.queue = w.queue[1:]
+		w.mu.Unlock()
+
+		select {
+		case w.events <- event:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Watch returns a channel receiving in mutation order an Event for every key starting with prefix
+// that is stored or deleted, through Add, Update and its variants, Delete and the batch operations.
+// Events are queued without bound so a slow reader never blocks writers,
+// cancel stops the watch, drops the undelivered events and closes the channel
+func (tree *RTree) Watch(prefix string) (<-chan Event, func()) {
+	w := &watcher{
+		prefix: prefix,
+		events: make(chan Event),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	tree.mu.Lock()
+	if tree.watches == nil {
+		tree.watches = newWatchRegistry()
+	}
+	registry := tree.watches
+	registry.add(w)
+	tree.mu.Unlock()
+
+	go w.run()
+	var once sync.Once
+	return w.events, func() {
+		once.Do(func() {
+			registry.remove(w)
+			close(w.done)
+		})
+	}
+}
+
+// walkPrefixesOf calls fn for every stored key that is a prefix of key, shortest first
+func (tree *RTree) walkPrefixesOf(key string, fn func(prefix string)) {
+	tree.mu.RLock()
+	defer tree.mu.RUnlock()
+	node := tree.Root
+	offset := 0
+	for offset < len(key) {
+		next := node.child(key[offset])
+		if next == nil || !strings.HasPrefix(key[offset:], next.Key) {
+			return
+		}
+		offset += len(next.Key)
+		if next.IsEnd {
+			fn(key[:offset])
+		}
+		node = next
+	}
+}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"rtree/router"
+)
+
+func newTestRouter() *router.Router {
+	rt := router.New()
+	reply := func(name string) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, req *http.Request) {
+			params := router.ParamsFromContext(req.Context())
+			fmt.Fprintf(w, "%s %v", name, params)
+		}
+	}
+	rt.HandleFunc(http.MethodGet, "/", reply("index"))
+	rt.HandleFunc(http.MethodGet, "/users", reply("users"))
+	rt.HandleFunc(http.MethodGet, "/users/new", reply("new-user"))
+	rt.HandleFunc(http.MethodGet, "/users/:id", reply("user"))
+	rt.HandleFunc(http.MethodPut, "/users/:id", reply("update-user"))
+	rt.HandleFunc(http.MethodGet, "/users/:id/posts/:post", reply("post"))
+	rt.HandleFunc(http.MethodGet, "/static/*filepath", reply("static"))
+	rt.HandleFunc(http.MethodGet, "/search/:query", reply("search"))
+	rt.HandleFunc(http.MethodGet, "/search/*rest", reply("search-all"))
+	return rt
+}
+
+func TestRouterMatch(t *testing.T) {
+
+	rt := newTestRouter()
+
+	expected := map[string]string{
+		"/":                    "index []",
+		"/users":               "users []",
+		"/users/new":           "new-user []",
+		"/users/newer":         "user [{id newer}]",
+		"/users/42":            "user [{id 42}]",
+		"/users/42/posts/7":    "post [{id 42} {post 7}]",
+		"/static/css/site.css": "static [{filepath css/site.css}]",
+		"/static/":             "static [{filepath }]",
+		"/search/radix":        "search [{query radix}]",
+		"/search/radix/tree":   "search-all [{rest radix/tree}]",
+	}
+	for path, body := range expected {
+		recorder := httptest.NewRecorder()
+		rt.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusOK || recorder.Body.String() != body {
+			t.Errorf(`GET %s error code=%d body=%s`, path, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+func TestRouterNotFoundAndMethodNotAllowed(t *testing.T) {
+
+	rt := newTestRouter()
+
+	recorder := httptest.NewRecorder()
+	rt.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/42/comments", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf(`Not found error code=%d`, recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	rt.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/users/42", nil))
+	if recorder.Code != http.StatusMethodNotAllowed || recorder.Header().Get("Allow") != "GET, PUT" {
+		t.Errorf(`Method not allowed error code=%d allow=%s`, recorder.Code, recorder.Header().Get("Allow"))
+	}
+
+	recorder = httptest.NewRecorder()
+	rt.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/users/42", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "update-user [{id 42}]" {
+		t.Errorf(`PUT error code=%d body=%s`, recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestRouterInvalidPatterns(t *testing.T) {
+
+	patterns := []string{"users", "/users/:", "/files/*path/more", "/users/new"}
+
+	for _, pattern := range patterns {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf(`Expected panic for pattern %s`, pattern)
+				}
+			}()
+			rt := newTestRouter()
+			rt.HandleFunc(http.MethodGet, pattern, func(http.ResponseWriter, *http.Request) {})
+		}()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	r "rtree/src"
 	"testing"
+	"time"
 )
 
 func TestAddNodesToChildren(t *testing.T) {
@@ -14,8 +15,8 @@ func TestAddNodesToChildren(t *testing.T) {
 
 	rtree.AddNodesToChildren(rtree.Root, node)
 
-	if len(rtree.Root.Children) != 1 {
-		t.Errorf(`rtree.Root.Children want 1 match  %d, nil`, len(rtree.Root.Children))
+	if len(rtree.Root.Children()) != 1 {
+		t.Errorf(`rtree.Root.Children want 1 match  %d, nil`, len(rtree.Root.Children()))
 	}
 }
 
@@ -30,15 +31,25 @@ func TestDeleteNodeFromChildren(t *testing.T) {
 	nodes := []*r.Node{node1, node2, node3}
 	rtree.AddNodesToChildren(rtree.Root, nodes...)
 
-	rtree.DeleteNodeFromChildren(rtree.Root, node1.Key)
+	// Siblings sharing a first byte are merged under their common prefix
+	shared, _ := rtree.Root.Child("test")
+	if len(rtree.Root.Children()) != 1 || shared == nil || len(shared.Children()) != 3 {
+		t.Fatalf(`rtree.Root.Children merge error len=%d`, len(rtree.Root.Children()))
+	}
+
+	rtree.DeleteNodeFromChildren(shared, "1")
 
-	_, exists1 := rtree.Root.Children[node1.Key]
-	value3, _ := rtree.Root.Children[node3.Key]
+	_, exists1 := shared.Child("1")
+	value3, _ := shared.Child("3")
 
-	if len(rtree.Root.Children) != 2 ||
+	if len(shared.Children()) != 2 ||
 		exists1 ||
-		value3.Key != "test3" {
-		t.Errorf(`rtree.Root.Children error len=%d`, len(rtree.Root.Children))
+		value3 == nil ||
+		value3.Value != "test value 3" {
+		t.Errorf(`rtree.Root.Children error len=%d`, len(shared.Children()))
+	}
+	if value, _ := rtree.Get("test2"); value != "test value 2" {
+		t.Errorf(`Not Found expected key test2 value=%s`, value)
 	}
 }
 
@@ -53,12 +64,12 @@ func TestAdd(t *testing.T) {
 	}
 	r.PrintNode(rtree.Root, true)
 
-	if len(rtree.Root.Children) != 3 {
-		t.Errorf(`rtree.Root.Children error len=%d`, len(rtree.Root.Children))
+	if len(rtree.Root.Children()) != 3 {
+		t.Errorf(`rtree.Root.Children error len=%d`, len(rtree.Root.Children()))
 	}
-	value, _ := rtree.Root.Children[keys[5]]
-	if len(value.Children) != 2 {
-		t.Errorf(`rtree.Root.Children[0].Children error len=%d`, len(value.Children))
+	value, _ := rtree.Root.Child(keys[5])
+	if len(value.Children()) != 2 {
+		t.Errorf(`rtree.Root.Children[0].Children error len=%d`, len(value.Children()))
 	}
 }
 
@@ -74,8 +85,8 @@ func TestAdd2(t *testing.T) {
 	}
 	r.PrintNode(rtree.Root, true)
 
-	if len(rtree.Root.Children) != 1 {
-		t.Errorf(`rtree.Root.Children error len=%d`, len(rtree.Root.Children))
+	if len(rtree.Root.Children()) != 1 {
+		t.Errorf(`rtree.Root.Children error len=%d`, len(rtree.Root.Children()))
 	}
 }
 
@@ -95,12 +106,14 @@ func TestAdd3(t *testing.T) {
 	}
 	r.PrintNode(rtree.Root, true)
 
-	if len(rtree.Root.Children) != 4 {
-		t.Errorf(`rtree.Root.Children error len=%d`, len(rtree.Root.Children))
+	if len(rtree.Root.Children()) != 3 {
+		t.Errorf(`rtree.Root.Children error len=%d`, len(rtree.Root.Children()))
 	}
-	value, _ := rtree.Root.Children[keys[0]]
-	if len(value.Children) != 2 {
-		t.Errorf(`rtree.Root.Children[0].Children error len=%d`, len(value.Children))
+	for _, edge := range []string{"b", "e7"} {
+		value, _ := rtree.Root.Child(edge)
+		if value == nil || len(value.Children()) != 2 {
+			t.Errorf(`rtree.Root.Children[%s].Children error`, edge)
+		}
 	}
 }
 
@@ -236,3 +249,148 @@ func TestDeleteNodeWithChildrenAndCompact(t *testing.T) {
 		t.Errorf(`Error deleting key %s`, keyToDelete)
 	}
 }
+
+func TestAddKeyRepeatingParentEdge(t *testing.T) {
+
+	rtree := r.NewRTree()
+
+	keys := []string{"ab", "abab", "ababab"}
+	for _, k := range keys {
+		if !rtree.Add(k, fmt.Sprintf("val of %s", k)) {
+			t.Errorf(`Fail to add key %s`, k)
+		}
+	}
+
+	for _, k := range keys {
+		node := rtree.Search(k)
+		if node == nil || node.Value != fmt.Sprintf("val of %s", k) {
+			t.Errorf(`Not Found expected key %s`, k)
+		}
+	}
+}
+
+func TestSearchKeyMatchingNestedEdge(t *testing.T) {
+
+	rtree := r.NewRTree()
+
+	keys := []string{"ab", "abaa"}
+	for _, k := range keys {
+		rtree.Add(k, fmt.Sprintf("val of %s", k))
+	}
+
+	key := "aa"
+	node := rtree.Search(key)
+	if node != nil {
+		t.Errorf(`Found unexpected key %s`, key)
+	}
+}
+
+func TestSplitKeepsNonTerminalNodes(t *testing.T) {
+
+	rtree := r.NewRTree()
+
+	keys := []string{"abc", "abd", "a"}
+	for _, k := range keys {
+		rtree.Add(k, fmt.Sprintf("val of %s", k))
+	}
+
+	key := "ab"
+	node := rtree.Search(key)
+	if node != nil {
+		t.Errorf(`Found unexpected key %s`, key)
+	}
+}
+
+func TestWalkPrefix(t *testing.T) {
+
+	rtree := r.NewRTree()
+
+	keys := []string{"ciao", "ciaone", "ciauz", "help", "helper", "cia", "test"}
+	for _, k := range keys {
+		rtree.Add(k, fmt.Sprintf("val of %s", k))
+	}
+
+	found := []string{}
+	rtree.WalkPrefix("ciao", func(key string, value string) bool {
+		if value != fmt.Sprintf("val of %s", key) {
+			t.Errorf(`Wrong value %s for key %s`, value, key)
+		}
+		found = append(found, key)
+		return true
+	})
+	if len(found) != 2 || found[0] != "ciao" || found[1] != "ciaone" {
+		t.Errorf(`WalkPrefix error found=%v`, found)
+	}
+
+	count := 0
+	rtree.WalkPrefix("", func(key string, value string) bool {
+		count++
+		return count < 3
+	})
+	if count != 3 {
+		t.Errorf(`WalkPrefix stop error count=%d`, count)
+	}
+}
+
+func TestWalkPrefixCallbackCallsTree(t *testing.T) {
+
+	rtree := r.NewRTree()
+
+	for i := 0; i < 600; i++ {
+		rtree.Add(fmt.Sprintf("key-%03d", i), fmt.Sprint(i))
+	}
+	rtree.AddWithTTL("expired", "v", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	done := make(chan []string)
+	go func() {
+		found := []string{}
+		rtree.WalkPrefix("key-", func(key string, value string) bool {
+			// Reading an expired key upgrades to the write lock to drop it
+			rtree.Get("expired")
+			if !rtree.Delete(key) {
+				t.Errorf(`Delete %s inside WalkPrefix failed`, key)
+			}
+			found = append(found, key)
+			return true
+		})
+		done <- found
+	}()
+
+	select {
+	case found := <-done:
+		if len(found) != 600 || found[0] != "key-000" || found[599] != "key-599" {
+			t.Errorf(`WalkPrefix error len=%d`, len(found))
+		}
+		left := 0
+		rtree.WalkPrefix("", func(string, string) bool {
+			left++
+			return true
+		})
+		if left != 0 {
+			t.Errorf(`Keys left after deleting them in WalkPrefix len=%d`, left)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf(`WalkPrefix deadlocked calling the tree from fn`)
+	}
+}
+
+func TestLongestPrefix(t *testing.T) {
+
+	rtree := r.NewRTree()
+
+	keys := []string{"/api", "/api/v1", "/api/v1/users", "/static"}
+	for _, k := range keys {
+		rtree.Add(k, fmt.Sprintf("val of %s", k))
+	}
+
+	key, value, found := rtree.LongestPrefix("/api/v1/user")
+	if !found || key != "/api/v1" || value != "val of /api/v1" {
+		t.Errorf(`LongestPrefix error key=%s value=%s`, key, value)
+	}
+
+	_, _, found = rtree.LongestPrefix("/ap")
+	if found {
+		t.Errorf(`LongestPrefix found unexpected key`)
+	}
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"rtree/src"
+)
+
+func allocTree() (*src.RTree, []string) {
+	tree := src.NewRTree()
+	keys := []string{"ciao", "ciaone", "ciauz", "help", "helper", "cia", "test"}
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, fmt.Sprintf("key/%d/value", i))
+	}
+	for _, k := range keys {
+		tree.Add(k, fmt.Sprintf("val of %s", k))
+	}
+	return tree, keys
+}
+
+func TestLookupsDoNotAllocate(t *testing.T) {
+
+	tree, keys := allocTree()
+	missing := []string{"ci", "helpers", "key/1000/value", "key/1/val", ""}
+
+	lookups := map[string]func(key string){
+		"Get":           func(key string) { tree.Get(key) },
+		"Contains":      func(key string) { tree.Contains(key) },
+		"Search":        func(key string) { tree.Search(key) },
+		"LongestPrefix": func(key string) { tree.LongestPrefix(key) },
+	}
+	for name, lookup := range lookups {
+		for _, batch := range [][]string{keys, missing} {
+			allocs := testing.AllocsPerRun(10, func() {
+				for _, k := range batch {
+					lookup(k)
+				}
+			})
+			if allocs != 0 {
+				t.Errorf(`%s allocated %v times per run`, name, allocs)
+			}
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	tree, keys := allocTree()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.Get(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkGetMissing(b *testing.B) {
+	tree, keys := allocTree()
+	for i := range keys {
+		keys[i] += "/missing"
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.Get(keys[i%len(keys)])
+	}
+}
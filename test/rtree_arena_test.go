@@ -0,0 +1,122 @@
+package test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestArenaRTree(t *testing.T) {
+
+	tree := src.NewArenaRTree(0)
+
+	keys := []string{"ciao", "ciaone", "ciauz", "cia", "help", "helper", "test"}
+	for _, k := range keys {
+		if !tree.Add(k, fmt.Sprintf("val of %s", k)) {
+			t.Errorf(`Add failed key=%s`, k)
+		}
+	}
+	if tree.Add("", "invalid") || tree.Add(src.ROOT, "invalid") {
+		t.Errorf(`Add accepted an invalid key`)
+	}
+	tree.Add("ciao", "val of ciao")
+	if tree.Len() != len(keys) {
+		t.Errorf(`Len error expected=%d got=%d`, len(keys), tree.Len())
+	}
+
+	for _, k := range keys {
+		if value, _ := tree.Get(k); value != fmt.Sprintf("val of %s", k) {
+			t.Errorf(`Not Found expected key %s value=%s`, k, value)
+		}
+	}
+	for _, k := range []string{"ci", "hel", "ciaon", "helpers", ""} {
+		if tree.Contains(k) {
+			t.Errorf(`Found unexpected key %s`, k)
+		}
+	}
+
+	for _, k := range []string{"cia", "help", "test"} {
+		if !tree.Delete(k) {
+			t.Errorf(`Delete failed key=%s`, k)
+		}
+	}
+	if tree.Delete("cia") || tree.Delete("missing") || tree.Delete("") {
+		t.Errorf(`Delete removed a missing key`)
+	}
+
+	found := []string{}
+	tree.WalkPrefix("", func(key string, value string) bool {
+		found = append(found, key)
+		return true
+	})
+	if fmt.Sprint(found) != "[ciao ciaone ciauz helper]" {
+		t.Errorf(`WalkPrefix error got=%v`, found)
+	}
+}
+
+func TestArenaRTreeReset(t *testing.T) {
+
+	tree := src.NewArenaRTree(100)
+	for i := 0; i < 100; i++ {
+		tree.Add(fmt.Sprintf("key%d", i), "value")
+	}
+	tree.Reset()
+	if tree.Len() != 0 || tree.Contains("key1") {
+		t.Errorf(`Reset left keys behind len=%d`, tree.Len())
+	}
+	tree.Add("key1", "again")
+	if value, _ := tree.Get("key1"); value != "again" || tree.Len() != 1 {
+		t.Errorf(`Add after Reset error value=%s`, value)
+	}
+}
+
+func TestArenaRTreeMatchesMap(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(1))
+	tree := src.NewArenaRTree(0)
+	expected := map[string]string{}
+	alphabet := "abcd"
+
+	for i := 0; i < 20000; i++ {
+		key := make([]byte, 1+rng.Intn(6))
+		for j := range key {
+			key[j] = alphabet[rng.Intn(len(alphabet))]
+		}
+		k := string(key)
+		if rng.Intn(3) == 0 {
+			_, exists := expected[k]
+			if tree.Delete(k) != exists {
+				t.Fatalf(`Delete result error key=%s`, k)
+			}
+			delete(expected, k)
+		} else {
+			v := fmt.Sprint(i)
+			tree.Add(k, v)
+			expected[k] = v
+		}
+	}
+
+	if tree.Len() != len(expected) {
+		t.Errorf(`Len error expected=%d got=%d`, len(expected), tree.Len())
+	}
+	for k, v := range expected {
+		if value, _ := tree.Get(k); value != v {
+			t.Errorf(`Get error key=%s expected=%s got=%s`, k, v, value)
+		}
+	}
+	previous := ""
+	count := 0
+	tree.WalkPrefix("", func(key string, value string) bool {
+		if key <= previous {
+			t.Errorf(`WalkPrefix out of order %s after %s`, key, previous)
+		}
+		previous = key
+		count++
+		return true
+	})
+	if count != len(expected) {
+		t.Errorf(`WalkPrefix visited %d keys expected %d`, count, len(expected))
+	}
+}
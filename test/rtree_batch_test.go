@@ -0,0 +1,141 @@
+package test
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestAddBatch(t *testing.T) {
+
+	rtree := src.NewRTree()
+	rtree.Add("ciao", "old")
+
+	entries := []src.Entry{
+		{Key: "ciauz", Value: "val of ciauz"},
+		{Key: "ciao", Value: "val of ciao"},
+		{Key: "", Value: "invalid"},
+		{Key: "cia", Value: "val of cia"},
+		{Key: "help", Value: "first"},
+		{Key: "ciaone", Value: "val of ciaone"},
+		{Key: "help", Value: "val of help"},
+		{Key: "helper", Value: "val of helper"},
+		{Key: src.ROOT, Value: "invalid"},
+	}
+	results := rtree.AddBatch(entries)
+
+	for i, entry := range entries {
+		valid := entry.Key != "" && entry.Key != src.ROOT
+		if results[i] != valid {
+			t.Errorf(`AddBatch result error key=%s result=%v`, entry.Key, results[i])
+		}
+	}
+	for _, k := range []string{"ciao", "ciaone", "ciauz", "cia", "help", "helper"} {
+		if value, _ := rtree.Get(k); value != fmt.Sprintf("val of %s", k) {
+			t.Errorf(`Not Found expected key %s value=%s`, k, value)
+		}
+	}
+	if rtree.Contains("ci") || rtree.Contains("hel") {
+		t.Errorf(`Found unexpected intermediate key`)
+	}
+}
+
+func TestDeleteBatch(t *testing.T) {
+
+	rtree := src.NewRTree()
+
+	keys := []string{"ciao", "ciaone", "ciauz", "help", "helper", "cia", "test"}
+	for _, k := range keys {
+		rtree.Add(k, fmt.Sprintf("val of %s", k))
+	}
+
+	toDelete := []string{"ciauz", "missing", "cia", "ci", "help", "ciauz", "test"}
+	results := rtree.DeleteBatch(toDelete)
+	expected := []bool{true, false, true, false, true, false, true}
+	for i := range toDelete {
+		if results[i] != expected[i] {
+			t.Errorf(`DeleteBatch result error key=%s result=%v`, toDelete[i], results[i])
+		}
+	}
+
+	for _, k := range []string{"ciao", "ciaone", "helper"} {
+		if !rtree.Contains(k) {
+			t.Errorf(`Not Found expected key %s`, k)
+		}
+	}
+	for _, k := range []string{"ciauz", "cia", "help", "test"} {
+		if rtree.Contains(k) {
+			t.Errorf(`Found deleted key %s`, k)
+		}
+	}
+	if _, exists := rtree.Root.Child("helper"); !exists {
+		t.Errorf(`DeleteBatch did not merge the single child of help`)
+	}
+}
+
+func TestBatchMatchesLoop(t *testing.T) {
+
+	// Batches run in key order when a hook observes them and in input order otherwise,
+	// odd rounds pass sorted input
+	for _, observed := range []bool{false, true} {
+		rng := rand.New(rand.NewSource(1))
+		batched := src.NewRTree()
+		looped := src.NewRTree()
+		if observed {
+			batched.AfterAdd(func(src.Event) {})
+		}
+
+		for round := 0; round < 20; round++ {
+			entries := make([]src.Entry, 200)
+			for i := range entries {
+				key := fmt.Sprintf("%x", rng.Intn(4096))
+				entries[i] = src.Entry{Key: key, Value: fmt.Sprintf("%d-%d", round, i)}
+			}
+			keys := make([]string, 100)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("%x", rng.Intn(4096))
+			}
+			if round%2 == 1 {
+				slices.SortStableFunc(entries, func(a src.Entry, b src.Entry) int {
+					return strings.Compare(a.Key, b.Key)
+				})
+				slices.Sort(keys)
+			}
+
+			batched.AddBatch(entries)
+			for _, entry := range entries {
+				looped.Add(entry.Key, entry.Value)
+			}
+
+			batchResults := batched.DeleteBatch(keys)
+			for i, k := range keys {
+				if looped.Delete(k) != batchResults[i] {
+					t.Fatalf(`DeleteBatch result differs from Delete key=%s`, k)
+				}
+			}
+			if err := batched.Validate(); err != nil {
+				t.Fatalf(`Batched tree invalid after round %d: %v`, round, err)
+			}
+		}
+
+		count := 0
+		looped.WalkPrefix("", func(key string, value string) bool {
+			count++
+			if batchedValue, _ := batched.Get(key); batchedValue != value {
+				t.Errorf(`Batched tree differs on key %s`, key)
+			}
+			return true
+		})
+		batched.WalkPrefix("", func(key string, value string) bool {
+			count--
+			return true
+		})
+		if count != 0 {
+			t.Errorf(`Batched tree size differs by %d`, count)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestBitTreeAddSearchDelete(t *testing.T) {
+
+	tree := src.NewBitTree()
+
+	keys := []string{"ciao", "ciaone", "ciauz", "help", "helper", "cia", "test"}
+	for _, k := range keys {
+		if !tree.Add(k, fmt.Sprintf("val of %s", k)) {
+			t.Errorf(`Fail to add key %s`, k)
+		}
+	}
+
+	for _, k := range keys {
+		node := tree.Search(k)
+		if node == nil || node.Value != fmt.Sprintf("val of %s", k) {
+			t.Errorf(`Not Found expected key %s`, k)
+		}
+	}
+	if tree.Search("ci") != nil || tree.Search("hello") != nil {
+		t.Errorf(`Found unexpected key`)
+	}
+
+	if !tree.Delete("cia") || tree.Search("cia") != nil || tree.Search("ciao") == nil {
+		t.Errorf(`Fail to delete key cia`)
+	}
+	if tree.Delete("cia") {
+		t.Errorf(`Deleted missing key cia`)
+	}
+	if tree.Len() != len(keys)-1 {
+		t.Errorf(`Len error len=%d`, tree.Len())
+	}
+}
+
+func TestBitTreeBranchesInsideBytes(t *testing.T) {
+
+	tree := src.NewBitTree()
+
+	// 0x40 and 0x41 differ only in the last bit
+	tree.AddBits([]byte{0x40}, 8, "0x40")
+	tree.AddBits([]byte{0x41}, 8, "0x41")
+	tree.AddBits([]byte{0x40}, 3, "010")
+
+	if tree.NodeCount() != 5 {
+		t.Errorf(`NodeCount error count=%d`, tree.NodeCount())
+	}
+
+	bits, value, found := tree.LongestPrefix([]byte{0x5f}, 8)
+	if !found || bits != 3 || value != "010" {
+		t.Errorf(`LongestPrefix error bits=%d value=%s`, bits, value)
+	}
+
+	node := tree.SearchBits([]byte{0x41}, 8)
+	if node == nil || node.Value != "0x41" {
+		t.Errorf(`Not Found expected key 0x41`)
+	}
+}
+
+func TestBitTreeMinimalNodeCount(t *testing.T) {
+
+	tree := src.NewBitTree()
+
+	keys := 1000
+	for i := 0; i < keys; i++ {
+		hash := sha256.Sum256([]byte(fmt.Sprintf("key-%d", i)))
+		tree.AddBits(hash[:], 256, fmt.Sprintf("%d", i))
+	}
+
+	// n leaves and n-1 branch nodes, the root being the first branch
+	if tree.NodeCount() != 2*keys-1 {
+		t.Errorf(`NodeCount error count=%d`, tree.NodeCount())
+	}
+
+	for i := 0; i < keys; i += 2 {
+		hash := sha256.Sum256([]byte(fmt.Sprintf("key-%d", i)))
+		if !tree.DeleteBits(hash[:], 256) {
+			t.Errorf(`Fail to delete key-%d`, i)
+		}
+	}
+	if tree.Len() != keys/2 || tree.NodeCount() != keys-1 {
+		t.Errorf(`NodeCount after delete error len=%d count=%d`, tree.Len(), tree.NodeCount())
+	}
+
+	walked := 0
+	tree.Walk(func(key []byte, bits int, value string) bool {
+		walked++
+		return true
+	})
+	if walked != keys/2 {
+		t.Errorf(`Walk error walked=%d`, walked)
+	}
+}
@@ -0,0 +1,151 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"sort"
+	"testing"
+
+	"rtree/src"
+)
+
+func sortedPairs(pairs ...string) iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for i := 0; i+1 < len(pairs); i += 2 {
+			if !yield(pairs[i], pairs[i+1]) {
+				return
+			}
+		}
+	}
+}
+
+func TestBuildFromSorted(t *testing.T) {
+
+	keys := []string{"ciao", "ciaone", "ciauz", "help", "helper", "cia", "test"}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, k := range keys {
+		pairs = append(pairs, k, fmt.Sprintf("val of %s", k))
+	}
+	built, err := src.BuildFromSorted(sortedPairs(pairs...), src.RejectDuplicates)
+	if err != nil {
+		t.Fatalf(`BuildFromSorted error %v`, err)
+	}
+
+	for _, k := range keys {
+		value, exists := built.Get(k)
+		if !exists || value != fmt.Sprintf("val of %s", k) {
+			t.Errorf(`Not Found expected key %s`, k)
+		}
+	}
+	if built.Contains("ci") || built.Contains("hel") {
+		t.Errorf(`Found unexpected intermediate key`)
+	}
+
+	// Same shape as repeated Add
+	added := src.NewRTree()
+	for _, k := range keys {
+		added.Add(k, fmt.Sprintf("val of %s", k))
+	}
+	if analyzeTreeStructure(built.Root, 0) != analyzeTreeStructure(added.Root, 0) {
+		t.Errorf(`Built tree differs from added tree`)
+	}
+
+	// The built tree keeps working with Add and Delete
+	built.Add("ci", "val of ci")
+	built.Delete("ciauz")
+	if !built.Contains("ci") || built.Contains("ciauz") || !built.Contains("ciao") {
+		t.Errorf(`Built tree update error`)
+	}
+}
+
+func TestBuildFromSortedUUIDs(t *testing.T) {
+
+	uuids := make([]string, 10000)
+	for i := range uuids {
+		uuids[i] = generateUUID()
+	}
+	sort.Strings(uuids)
+
+	pairs := []string{}
+	for _, uuid := range uuids {
+		pairs = append(pairs, uuid, uuid)
+	}
+	built, err := src.BuildFromSorted(sortedPairs(pairs...), src.RejectDuplicates)
+	if err != nil {
+		t.Fatalf(`BuildFromSorted error %v`, err)
+	}
+	if err := built.Validate(); err != nil {
+		t.Errorf(`BuildFromSorted built an invalid tree: %v`, err)
+	}
+	for _, uuid := range uuids {
+		if value, _ := built.Get(uuid); value != uuid {
+			t.Errorf(`Not Found expected key %s`, uuid)
+		}
+	}
+}
+
+func TestBuildFromSortedErrorsAndPolicies(t *testing.T) {
+
+	_, err := src.BuildFromSorted(sortedPairs("b", "1", "a", "2"), src.RejectDuplicates)
+	if !errors.Is(err, src.ErrUnsorted) {
+		t.Errorf(`Unsorted input error %v`, err)
+	}
+
+	_, err = src.BuildFromSorted(sortedPairs("a", "1", "a", "2"), src.RejectDuplicates)
+	if !errors.Is(err, src.ErrDuplicateKey) {
+		t.Errorf(`Duplicate input error %v`, err)
+	}
+
+	_, err = src.BuildFromSorted(sortedPairs("", "1"), src.RejectDuplicates)
+	if !errors.Is(err, src.ErrEmptyKey) {
+		t.Errorf(`Empty key error %v`, err)
+	}
+
+	first, _ := src.BuildFromSorted(sortedPairs("a", "1", "a", "2", "ab", "3"), src.KeepFirst)
+	if value, _ := first.Get("a"); value != "1" {
+		t.Errorf(`KeepFirst error value=%s`, value)
+	}
+
+	last, _ := src.BuildFromSorted(sortedPairs("a", "1", "a", "2", "ab", "3"), src.KeepLast)
+	if value, _ := last.Get("a"); value != "2" {
+		t.Errorf(`KeepLast error value=%s`, value)
+	}
+}
+
+func TestBuildFromSortedSplitsEdges(t *testing.T) {
+
+	// Each key diverges from the previous one at a different depth, inside an edge or at a node
+	keys := []string{"a", "abcdef", "abcdxy", "abcz", "abd", "b", "bcdefgh", "bcdefgi", "bcx", "c"}
+
+	pairs := []string{}
+	for _, k := range keys {
+		pairs = append(pairs, k, fmt.Sprintf("val of %s", k))
+	}
+	built, err := src.BuildFromSorted(sortedPairs(pairs...), src.RejectDuplicates)
+	if err != nil {
+		t.Fatalf(`BuildFromSorted error %v`, err)
+	}
+	if err := built.Validate(); err != nil {
+		t.Fatalf(`Built tree invalid: %v`, err)
+	}
+
+	found := []string{}
+	built.WalkPrefix("", func(key string, value string) bool {
+		if value != fmt.Sprintf("val of %s", key) {
+			t.Errorf(`Wrong value %s for key %s`, value, key)
+		}
+		found = append(found, key)
+		return true
+	})
+	if fmt.Sprint(found) != fmt.Sprint(keys) {
+		t.Errorf(`BuildFromSorted keys error got=%v`, found)
+	}
+	for _, k := range []string{"ab", "abc", "abcd", "bc", "bcdefg"} {
+		if built.Contains(k) {
+			t.Errorf(`Found unexpected intermediate key %s`, k)
+		}
+	}
+}
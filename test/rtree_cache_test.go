@@ -0,0 +1,175 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rtree/src"
+)
+
+func cacheKeys(cache *src.CacheRTree, prefix string) string {
+	keys := []string{}
+	cache.WalkPrefix(prefix, func(key string, value string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return strings.Join(keys, ",")
+}
+
+func TestCacheLRU(t *testing.T) {
+
+	evicted := []string{}
+	cache := src.NewCacheRTree(src.CacheOptions{
+		Policy:     src.EvictLRU,
+		MaxEntries: 3,
+		OnEvict: func(key string, value string) {
+			evicted = append(evicted, key+"="+value)
+		},
+	})
+
+	cache.Add("ciao", "1")
+	cache.Add("ciaone", "2")
+	cache.Add("ciauz", "3")
+	cache.Get("ciao")
+	cache.Add("help", "4")
+	cache.Add("ciauz", "5")
+	cache.Add("helper", "6")
+
+	if fmt.Sprint(evicted) != "[ciaone=2 ciao=1]" {
+		t.Errorf(`LRU eviction error got=%v`, evicted)
+	}
+	if keys := cacheKeys(cache, ""); keys != "ciauz,help,helper" || cache.Len() != 3 {
+		t.Errorf(`LRU live keys error got=%s`, keys)
+	}
+	if key, _, found := cache.LongestPrefix("helpers"); !found || key != "helper" {
+		t.Errorf(`LongestPrefix error got=%s`, key)
+	}
+	if !cache.Delete("help") || cache.Delete("help") || len(evicted) != 2 {
+		t.Errorf(`Delete error`)
+	}
+}
+
+func TestCacheLFU(t *testing.T) {
+
+	evicted := []string{}
+	cache := src.NewCacheRTree(src.CacheOptions{
+		Policy:     src.EvictLFU,
+		MaxEntries: 3,
+		OnEvict: func(key string, value string) {
+			evicted = append(evicted, key)
+		},
+	})
+
+	cache.Add("a", "1")
+	cache.Add("b", "2")
+	cache.Add("c", "3")
+	for range 3 {
+		cache.Get("a")
+	}
+	cache.Get("b")
+	cache.Get("c")
+	cache.Get("c")
+
+	// Counts are a=4 b=2 c=3, d enters with the lowest count but is never its own victim
+	cache.Add("d", "4")
+	cache.Add("e", "5")
+	cache.Add("e", "5")
+	cache.Add("f", "6")
+
+	if fmt.Sprint(evicted) != "[b d e]" {
+		t.Errorf(`LFU eviction error got=%v`, evicted)
+	}
+	if keys := cacheKeys(cache, ""); keys != "a,c,f" {
+		t.Errorf(`LFU live keys error got=%s`, keys)
+	}
+}
+
+func TestCacheMaxBytes(t *testing.T) {
+
+	cache := src.NewCacheRTree(src.CacheOptions{MaxBytes: 20})
+
+	if cache.Add("key", strings.Repeat("x", 18)) {
+		t.Errorf(`Add accepted an entry larger than MaxBytes`)
+	}
+	cache.Add("k1", "1234")
+	cache.Add("k2", "1234")
+	cache.Add("k3", "1234")
+	if cache.Bytes() != 18 || cache.Len() != 3 {
+		t.Errorf(`Bytes error got=%d`, cache.Bytes())
+	}
+
+	// Growing k3 pushes out the oldest entries, never k3 itself
+	cache.Add("k3", "1234567890")
+	if keys := cacheKeys(cache, "k"); keys != "k2,k3" || cache.Bytes() != 18 {
+		t.Errorf(`MaxBytes eviction error keys=%s bytes=%d`, keys, cache.Bytes())
+	}
+	if value, _ := cache.Get("k3"); value != "1234567890" {
+		t.Errorf(`Get error value=%s`, value)
+	}
+}
+
+func TestCacheChurn(t *testing.T) {
+
+	for _, policy := range []src.EvictionPolicy{src.EvictLRU, src.EvictLFU} {
+		cache := src.NewCacheRTree(src.CacheOptions{Policy: policy, MaxEntries: 100})
+		for i := 0; i < 10000; i++ {
+			cache.Add(fmt.Sprintf("session/%d", i), "token")
+			cache.Get(fmt.Sprintf("session/%d", i/2))
+		}
+		count := 0
+		cache.WalkPrefix("session/", func(key string, value string) bool {
+			if !cache.Contains(key) {
+				t.Errorf(`Tree holds evicted key %s`, key)
+			}
+			count++
+			return true
+		})
+		if count != 100 || cache.Len() != 100 {
+			t.Errorf(`Policy %d holds %d keys`, policy, count)
+		}
+	}
+}
+
+func TestCacheWalkPrefixCallsCache(t *testing.T) {
+
+	cache := src.NewCacheRTree(src.CacheOptions{MaxEntries: 50})
+	for i := 0; i < 50; i++ {
+		cache.Add(fmt.Sprintf("session/%d", i), "token")
+	}
+
+	done := make(chan bool)
+	go func() {
+		// Concurrent writers lock the cache then the tree, fn must not hold the tree while it locks the cache
+		var writers sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			writers.Add(1)
+			go func(w int) {
+				defer writers.Done()
+				for i := 0; i < 1000; i++ {
+					cache.Add(fmt.Sprintf("session/%d-%d", w, i), "token")
+				}
+			}(w)
+		}
+		for i := 0; i < 100; i++ {
+			cache.WalkPrefix("session/", func(key string, value string) bool {
+				cache.Get(key)
+				cache.Add(key+"/walked", value)
+				return true
+			})
+		}
+		writers.Wait()
+		done <- true
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf(`WalkPrefix deadlocked with concurrent writers`)
+	}
+	if cache.Len() != 50 {
+		t.Errorf(`Cache holds %d keys`, cache.Len())
+	}
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"rtree/src"
+)
+
+// TestRTreeNodeGrowth fills a single node up to 256 children and empties it again
+// so every child layout is grown into and shrunk back from
+func TestRTreeNodeGrowth(t *testing.T) {
+
+	rtree := src.NewRTree()
+	rtree.Add("k", "k")
+	for i := 255; i > 0; i-- {
+		rtree.Add(string([]byte{'k', byte(i)}), fmt.Sprint(i))
+	}
+	if err := rtree.Validate(); err != nil {
+		t.Fatalf(`Validate error after growth: %v`, err)
+	}
+	node, _ := rtree.Root.Child("k")
+	if len(node.Children()) != 255 {
+		t.Fatalf(`Children error len=%d`, len(node.Children()))
+	}
+
+	previous := -1
+	rtree.WalkPrefix("k", func(key string, value string) bool {
+		if len(key) == 2 {
+			if int(key[1]) <= previous {
+				t.Fatalf(`WalkPrefix out of order %d after %d`, key[1], previous)
+			}
+			previous = int(key[1])
+		}
+		return true
+	})
+
+	for i := 1; i < 256; i++ {
+		if !rtree.Delete(string([]byte{'k', byte(i)})) {
+			t.Fatalf(`Delete failed key %d`, i)
+		}
+		for j := i + 1; j < 256; j += 17 {
+			if value, _ := rtree.Get(string([]byte{'k', byte(j)})); value != fmt.Sprint(j) {
+				t.Fatalf(`Not Found key %d after deleting %d`, j, i)
+			}
+		}
+		if err := rtree.Validate(); err != nil {
+			t.Fatalf(`Validate error after deleting %d: %v`, i, err)
+		}
+	}
+	if value, _ := rtree.Get("k"); value != "k" || len(node.Children()) != 0 {
+		t.Errorf(`Shrink error value=%s len=%d`, value, len(node.Children()))
+	}
+}
+
+func uuidWorkload(n int) []string {
+	uuids := make([]string, n)
+	for i := range uuids {
+		uuids[i] = generateUUID()
+	}
+	return uuids
+}
+
+func BenchmarkRTreeAddUUID(b *testing.B) {
+	uuids := uuidWorkload(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rtree := src.NewRTree()
+		for _, uuid := range uuids {
+			rtree.Add(uuid, uuid)
+		}
+	}
+}
+
+func BenchmarkRTreeGetUUID(b *testing.B) {
+	uuids := uuidWorkload(100000)
+	rtree := src.NewRTree()
+	for _, uuid := range uuids {
+		rtree.Add(uuid, uuid)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rtree.Get(uuids[i%len(uuids)])
+	}
+}
+
+// BenchmarkRTreeUUIDMemory reports the heap retained per key, keys and values are shared with the workload
+func BenchmarkRTreeUUIDMemory(b *testing.B) {
+	uuids := uuidWorkload(100000)
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		rtree := src.NewRTree()
+		for _, uuid := range uuids {
+			rtree.Add(uuid, uuid)
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		runtime.KeepAlive(rtree)
+		b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/float64(len(uuids)), "bytes/key")
+	}
+}
@@ -0,0 +1,270 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestDurableRTreeReplay(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "tree.wal")
+
+	tree, err := src.OpenDurableRTree(path, src.DurableOptions{})
+	if err != nil {
+		t.Fatalf(`Open error %v`, err)
+	}
+	keys := []string{"ciao", "ciaone", "ciauz", "help", "helper", "cia", "test"}
+	for _, k := range keys {
+		if err := tree.Add(k, fmt.Sprintf("val of %s", k)); err != nil {
+			t.Errorf(`Add %s error %v`, k, err)
+		}
+	}
+	tree.Add("help", "new val of help")
+	if deleted, err := tree.Delete("ciauz"); !deleted || err != nil {
+		t.Errorf(`Delete error deleted=%v err=%v`, deleted, err)
+	}
+	if deleted, _ := tree.Delete("missing"); deleted {
+		t.Errorf(`Deleted missing key`)
+	}
+	if err := tree.Add("", "v"); err == nil {
+		t.Errorf(`Add accepted an empty key`)
+	}
+	if err := tree.Close(); err != nil {
+		t.Errorf(`Close error %v`, err)
+	}
+	if err := tree.Add("late", "v"); err != src.ErrClosed {
+		t.Errorf(`Add after close error %v`, err)
+	}
+
+	reopened, err := src.OpenDurableRTree(path, src.DurableOptions{})
+	if err != nil {
+		t.Fatalf(`Reopen error %v`, err)
+	}
+	defer reopened.Close()
+
+	if value, _ := reopened.Get("help"); value != "new val of help" {
+		t.Errorf(`Replayed overwrite error value=%s`, value)
+	}
+	if reopened.Contains("ciauz") {
+		t.Errorf(`Replayed delete error`)
+	}
+	for _, k := range []string{"ciao", "ciaone", "helper", "cia", "test"} {
+		if value, _ := reopened.Get(k); value != fmt.Sprintf("val of %s", k) {
+			t.Errorf(`Not Found expected key %s`, k)
+		}
+	}
+}
+
+func TestDurableRTreeTornRecord(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "tree.wal")
+
+	tree, _ := src.OpenDurableRTree(path, src.DurableOptions{NoSync: true})
+	tree.Add("first", "1")
+	tree.Add("second", "2")
+	tree.Close()
+
+	info, _ := os.Stat(path)
+	// Cut the last record in half as a crash during the write would
+	os.Truncate(path, info.Size()-3)
+
+	reopened, err := src.OpenDurableRTree(path, src.DurableOptions{})
+	if err != nil {
+		t.Fatalf(`Open torn log error %v`, err)
+	}
+	if !reopened.Contains("first") || reopened.Contains("second") {
+		t.Errorf(`Torn record replay error`)
+	}
+	reopened.Add("third", "3")
+	reopened.Close()
+
+	reopened, _ = src.OpenDurableRTree(path, src.DurableOptions{})
+	defer reopened.Close()
+	if !reopened.Contains("first") || !reopened.Contains("third") {
+		t.Errorf(`Write after torn record lost`)
+	}
+}
+
+func TestDurableRTreeZeroFilledTail(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "tree.wal")
+
+	tree, _ := src.OpenDurableRTree(path, src.DurableOptions{})
+	tree.Add("first", "1")
+	tree.Add("second", "2")
+	tree.Close()
+
+	// The file was extended before the next record landed, as some filesystems do on a crash
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, append(data, make([]byte, 4096)...), 0o644)
+
+	reopened, err := src.OpenDurableRTree(path, src.DurableOptions{})
+	if err != nil {
+		t.Fatalf(`Open zero filled log error %v`, err)
+	}
+	if !reopened.Contains("first") || !reopened.Contains("second") {
+		t.Errorf(`Zero filled tail replay error`)
+	}
+	reopened.Add("third", "3")
+	reopened.Close()
+
+	reopened, err = src.OpenDurableRTree(path, src.DurableOptions{})
+	if err != nil {
+		t.Fatalf(`Reopen after zero filled tail error %v`, err)
+	}
+	if !reopened.Contains("second") || !reopened.Contains("third") {
+		t.Errorf(`Write after zero filled tail lost`)
+	}
+	reopened.Close()
+
+	// Data after the zeros means the log was damaged in the middle
+	data, _ = os.ReadFile(path)
+	zeroed := append(append(data, make([]byte, 64)...), data...)
+	os.WriteFile(path, zeroed, 0o644)
+	if reopened, err := src.OpenDurableRTree(path, src.DurableOptions{}); err == nil {
+		reopened.Close()
+		t.Errorf(`Open accepted zeros followed by records`)
+	}
+}
+
+func TestDurableRTreeCorruptRecord(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "tree.wal")
+
+	tree, _ := src.OpenDurableRTree(path, src.DurableOptions{})
+	tree.Add("first", "1")
+	tree.Add("second", "2")
+	tree.Close()
+
+	data, _ := os.ReadFile(path)
+	data[len(data)-1] ^= 0xff
+	os.WriteFile(path, data, 0o644)
+
+	reopened, err := src.OpenDurableRTree(path, src.DurableOptions{})
+	if err != nil {
+		t.Fatalf(`Open corrupt log error %v`, err)
+	}
+	defer reopened.Close()
+	if !reopened.Contains("first") || reopened.Contains("second") {
+		t.Errorf(`Corrupt record replay error`)
+	}
+}
+
+func TestDurableRTreeCorruptMiddleRecord(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "tree.wal")
+
+	tree, _ := src.OpenDurableRTree(path, src.DurableOptions{})
+	for _, k := range []string{"a", "b", "c", "d"} {
+		tree.Add(k, "1")
+	}
+	tree.Close()
+
+	data, _ := os.ReadFile(path)
+	recordSize := len(data) / 4
+	// Flip the value byte of the second record, the two records after it are intact
+	data[2*recordSize-1] ^= 0xff
+	os.WriteFile(path, data, 0o644)
+
+	if reopened, err := src.OpenDurableRTree(path, src.DurableOptions{}); err == nil {
+		reopened.Close()
+		t.Fatalf(`Open accepted a log damaged before its last record`)
+	}
+	if after, _ := os.ReadFile(path); len(after) != len(data) {
+		t.Errorf(`Damaged log truncated from %d to %d bytes`, len(data), len(after))
+	}
+}
+
+func TestDurableRTreeSnapshot(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "tree.wal")
+
+	tree, _ := src.OpenDurableRTree(path, src.DurableOptions{NoSync: true})
+	for i := 0; i < 100; i++ {
+		tree.Add(fmt.Sprintf("key-%03d", i), fmt.Sprintf("%d", i))
+	}
+	tree.Delete("key-000")
+
+	if err := tree.Snapshot(); err != nil {
+		t.Fatalf(`Snapshot error %v`, err)
+	}
+	if tree.LogSize() != 0 {
+		t.Errorf(`Log not truncated size=%d`, tree.LogSize())
+	}
+
+	tree.Add("key-100", "100")
+	tree.Delete("key-001")
+	tree.Close()
+
+	reopened, err := src.OpenDurableRTree(path, src.DurableOptions{})
+	if err != nil {
+		t.Fatalf(`Reopen error %v`, err)
+	}
+	defer reopened.Close()
+
+	count := 0
+	reopened.WalkPrefix("key-", func(key string, value string) bool {
+		count++
+		return true
+	})
+	if count != 99 || reopened.Contains("key-000") || reopened.Contains("key-001") || !reopened.Contains("key-100") {
+		t.Errorf(`Recovery from snapshot and log error count=%d`, count)
+	}
+}
+
+func TestDurableRTreeBackgroundSnapshot(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "tree.wal")
+
+	tree, _ := src.OpenDurableRTree(path, src.DurableOptions{NoSync: true, SnapshotEntries: 50})
+	for i := 0; i < 1000; i++ {
+		if err := tree.Add(fmt.Sprintf("key-%04d", i), fmt.Sprintf("%d", i)); err != nil {
+			t.Fatalf(`Add error %v`, err)
+		}
+	}
+	if err := tree.Close(); err != nil {
+		t.Errorf(`Close error %v`, err)
+	}
+	if err := tree.SnapshotErr(); err != nil {
+		t.Errorf(`Background snapshot error %v`, err)
+	}
+
+	info, err := os.Stat(path + ".snap")
+	if err != nil || info.Size() == 0 {
+		t.Fatalf(`Snapshot file missing err=%v`, err)
+	}
+	logInfo, _ := os.Stat(path)
+	if logInfo.Size() >= info.Size() {
+		t.Errorf(`Log not compacted log=%d snapshot=%d`, logInfo.Size(), info.Size())
+	}
+
+	reopened, _ := src.OpenDurableRTree(path, src.DurableOptions{})
+	defer reopened.Close()
+	for i := 0; i < 1000; i++ {
+		if value, _ := reopened.Get(fmt.Sprintf("key-%04d", i)); value != fmt.Sprintf("%d", i) {
+			t.Errorf(`Not Found expected key key-%04d`, i)
+		}
+	}
+}
+
+func TestDurableRTreeCorruptSnapshot(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "tree.wal")
+
+	tree, _ := src.OpenDurableRTree(path, src.DurableOptions{})
+	tree.Add("first", "1")
+	tree.Snapshot()
+	tree.Close()
+
+	data, _ := os.ReadFile(path + ".snap")
+	os.WriteFile(path+".snap", data[:len(data)-2], 0o644)
+
+	if _, err := src.OpenDurableRTree(path, src.DurableOptions{}); !errors.Is(err, src.ErrCorruptSnapshot) {
+		t.Errorf(`Open with corrupt snapshot error %v`, err)
+	}
+}
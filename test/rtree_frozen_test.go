@@ -0,0 +1,110 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestFrozenRTree(t *testing.T) {
+
+	rtree := src.NewRTree()
+
+	keys := []string{"ciao", "ciaone", "ciauz", "help", "helper", "cia", "test"}
+	for _, k := range keys {
+		rtree.Add(k, fmt.Sprintf("val of %s", k))
+	}
+
+	path := filepath.Join(t.TempDir(), "tree.frozen")
+	if err := src.WriteFrozenFile(rtree, path); err != nil {
+		t.Fatalf(`WriteFrozenFile error %v`, err)
+	}
+	frozen, err := src.OpenFrozen(path)
+	if err != nil {
+		t.Fatalf(`OpenFrozen error %v`, err)
+	}
+	defer frozen.Close()
+
+	for _, k := range keys {
+		value, exists := frozen.Get(k)
+		if !exists || value != fmt.Sprintf("val of %s", k) {
+			t.Errorf(`Not Found expected key %s`, k)
+		}
+	}
+	for _, k := range []string{"ci", "hel", "uz", "hello", "", "testing"} {
+		if frozen.Contains(k) {
+			t.Errorf(`Found unexpected key %s`, k)
+		}
+	}
+
+	found := []string{}
+	frozen.WalkPrefix("cia", func(key string, value string) bool {
+		found = append(found, key)
+		return true
+	})
+	if strings.Join(found, ",") != "cia,ciao,ciaone,ciauz" {
+		t.Errorf(`WalkPrefix error found=%v`, found)
+	}
+
+	found = []string{}
+	frozen.WalkPrefix("hel", func(key string, value string) bool {
+		found = append(found, key)
+		return true
+	})
+	if strings.Join(found, ",") != "help,helper" {
+		t.Errorf(`WalkPrefix inside edge error found=%v`, found)
+	}
+
+	key, value, matched := frozen.LongestPrefix("helpers")
+	if !matched || key != "helper" || value != "val of helper" {
+		t.Errorf(`LongestPrefix error key=%s value=%s`, key, value)
+	}
+}
+
+func TestFrozenRTreeMatchesTree(t *testing.T) {
+
+	rtree := src.NewRTree()
+	for i := 0; i < 5000; i++ {
+		uuid := generateUUID()
+		rtree.Add(uuid, uuid)
+	}
+
+	path := filepath.Join(t.TempDir(), "uuids.frozen")
+	src.WriteFrozenFile(rtree, path)
+	frozen, err := src.OpenFrozen(path)
+	if err != nil {
+		t.Fatalf(`OpenFrozen error %v`, err)
+	}
+	defer frozen.Close()
+
+	count := 0
+	rtree.WalkPrefix("", func(key string, value string) bool {
+		count++
+		if frozenValue, exists := frozen.Get(key); !exists || frozenValue != value {
+			t.Errorf(`Not Found expected key %s`, key)
+		}
+		return true
+	})
+	frozenCount := 0
+	frozen.WalkPrefix("", func(key string, value string) bool {
+		frozenCount++
+		return true
+	})
+	if count != 5000 || frozenCount != count {
+		t.Errorf(`Frozen walk error count=%d frozen=%d`, count, frozenCount)
+	}
+}
+
+func TestFrozenRTreeCorruptFile(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "tree.frozen")
+
+	os.WriteFile(path, []byte("not a frozen tree"), 0o644)
+	if _, err := src.OpenFrozen(path); err != src.ErrCorruptFrozen {
+		t.Errorf(`OpenFrozen corrupt file error %v`, err)
+	}
+}
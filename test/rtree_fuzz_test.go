@@ -0,0 +1,126 @@
+package test
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"rtree/src"
+)
+
+const (
+	fuzzAdd = iota
+	fuzzDelete
+	fuzzSearch
+	fuzzCompact
+	fuzzOps
+)
+
+// fuzzAlphabet is small so generated keys share prefixes and collide often
+const fuzzAlphabet = "abcROT"
+
+func fuzzKey(data []byte) (string, []byte) {
+	length := int(data[0] % 6)
+	data = data[1:]
+	key := make([]byte, 0, length)
+	for len(key) < length && len(data) > 0 {
+		key = append(key, fuzzAlphabet[int(data[0])%len(fuzzAlphabet)])
+		data = data[1:]
+	}
+	return string(key), data
+}
+
+func FuzzRTree(f *testing.F) {
+	f.Add([]byte{fuzzAdd, 4, 0, 1, 2, 0, fuzzAdd, 2, 0, 1, fuzzDelete, 4, 0, 1, 2, 0, fuzzCompact})
+	f.Add([]byte{fuzzAdd, 3, 0, 0, 0, fuzzAdd, 3, 0, 0, 1, fuzzDelete, 3, 0, 0, 0, fuzzSearch, 3, 0, 0, 1})
+	f.Add([]byte{fuzzAdd, 4, 3, 4, 4, 5, fuzzSearch, 4, 3, 4, 4, 5})
+	f.Add([]byte{fuzzAdd, 2, 0, 1, fuzzAdd, 2, 0, 2, fuzzAdd, 1, 0, fuzzDelete, 1, 0, fuzzCompact, fuzzAdd, 3, 0, 1, 1})
+
+	f.Fuzz(checkRTreeOps)
+}
+
+// TestRTreeProperties runs random operation sequences so plain go test covers more than the fuzz seeds
+func TestRTreeProperties(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		data := make([]byte, rng.Intn(200))
+		rng.Read(data)
+		checkRTreeOps(t, data)
+	}
+}
+
+// checkRTreeOps decodes data into Add, Delete, Search and Compact calls applied to an RTree
+// and to a reference map, failing on the first divergence or broken invariant
+func checkRTreeOps(t *testing.T, data []byte) {
+	tree := src.NewRTree()
+	expected := map[string]string{}
+	ops := []string{}
+
+	for step := 0; len(data) > 0; step++ {
+		op := int(data[0]) % fuzzOps
+		data = data[1:]
+		if op == fuzzCompact {
+			ops = append(ops, "Compact")
+			tree.Compact()
+			if err := tree.Validate(); err != nil {
+				t.Fatalf("after %v: %v", ops, err)
+			}
+			continue
+		}
+		if len(data) == 0 {
+			break
+		}
+		key := ""
+		key, data = fuzzKey(data)
+		valid := key != "" && key != src.ROOT
+
+		switch op {
+		case fuzzAdd:
+			value := fmt.Sprint(step)
+			ops = append(ops, fmt.Sprintf("Add(%q)", key))
+			if tree.Add(key, value) != valid {
+				t.Fatalf("after %v: Add returned %v", ops, !valid)
+			}
+			if valid {
+				expected[key] = value
+			}
+		case fuzzDelete:
+			ops = append(ops, fmt.Sprintf("Delete(%q)", key))
+			_, exists := expected[key]
+			if tree.Delete(key) != exists {
+				t.Fatalf("after %v: Delete returned %v", ops, !exists)
+			}
+			delete(expected, key)
+		case fuzzSearch:
+			ops = append(ops, fmt.Sprintf("Search(%q)", key))
+			node := tree.Search(key)
+			value, exists := expected[key]
+			if (node != nil) != exists || (exists && node.Value != value) {
+				t.Fatalf("after %v: Search returned %v", ops, node)
+			}
+		}
+		if err := tree.Validate(); err != nil {
+			t.Fatalf("after %v: %v", ops, err)
+		}
+	}
+
+	keys := []string{}
+	tree.WalkPrefix("", func(key string, value string) bool {
+		if expected[key] != value {
+			t.Fatalf("after %v: WalkPrefix %q=%q expected %q", ops, key, value, expected[key])
+		}
+		keys = append(keys, key)
+		return true
+	})
+	if len(keys) != len(expected) || !sort.StringsAreSorted(keys) {
+		t.Fatalf("after %v: WalkPrefix visited %s expected %d keys", ops, strings.Join(keys, ","), len(expected))
+	}
+	for key, value := range expected {
+		if got, exists := tree.Get(key); !exists || got != value {
+			t.Fatalf("after %v: Get(%q)=%q expected %q", ops, key, got, value)
+		}
+	}
+}
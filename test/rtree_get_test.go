@@ -0,0 +1,82 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestGetAndContains(t *testing.T) {
+
+	rtree := src.NewRTree()
+
+	keys := []string{"ciao", "ciaone", "ciauz", "help", "helper", "cia", "test"}
+	for _, k := range keys {
+		rtree.Add(k, fmt.Sprintf("val of %s", k))
+	}
+
+	for _, k := range keys {
+		value, exists := rtree.Get(k)
+		if !exists || value != fmt.Sprintf("val of %s", k) || !rtree.Contains(k) {
+			t.Errorf(`Not Found expected key %s`, k)
+		}
+	}
+
+	for _, k := range []string{"ci", "hel", "uz", "hello", ""} {
+		if _, exists := rtree.Get(k); exists || rtree.Contains(k) {
+			t.Errorf(`Found unexpected key %s`, k)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+
+	rtree := src.NewRTree()
+
+	keys := []string{"ciao", "ciaone", "ciauz", "cia"}
+	for _, k := range keys {
+		rtree.Add(k, fmt.Sprintf("val of %s", k))
+	}
+
+	root := rtree.Cursor()
+	if !root.IsRoot() || root.IsEnd() || root.Key() != "" || root.Edge() != "" {
+		t.Errorf(`Root cursor error`)
+	}
+
+	cursor, found := rtree.Seek("cia")
+	if !found || !cursor.IsEnd() || cursor.Value() != "val of cia" {
+		t.Errorf(`Seek error found=%v`, found)
+	}
+
+	edges := []string{}
+	keysFound := []string{}
+	for _, child := range cursor.Children() {
+		edges = append(edges, child.Edge())
+		keysFound = append(keysFound, child.Key())
+	}
+	if strings.Join(edges, ",") != "o,uz" || strings.Join(keysFound, ",") != "ciao,ciauz" {
+		t.Errorf(`Children error edges=%v keys=%v`, edges, keysFound)
+	}
+
+	if _, found := rtree.Seek("ciaon"); found {
+		t.Errorf(`Seek found a key ending inside an edge`)
+	}
+}
+
+func TestSearchDoesNotCorruptDelete(t *testing.T) {
+
+	rtree := src.NewRTree()
+
+	keys := []string{"ciao", "ciaone", "test"}
+	for _, k := range keys {
+		rtree.Add(k, fmt.Sprintf("val of %s", k))
+	}
+
+	// A stale lookup must not influence which parent Delete updates
+	rtree.Search("ciaone")
+	if !rtree.Delete("test") || rtree.Contains("test") || !rtree.Contains("ciaone") {
+		t.Errorf(`Delete error after Search`)
+	}
+}
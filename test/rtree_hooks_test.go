@@ -0,0 +1,156 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestHooks(t *testing.T) {
+
+	rtree := src.NewRTree()
+	audit := []string{}
+
+	rtree.BeforeAdd(func(key string, value string) (string, bool) {
+		return strings.ToUpper(value), !strings.HasPrefix(key, "tmp")
+	})
+	rtree.BeforeAdd(func(key string, value string) (string, bool) {
+		return value + "!", true
+	})
+	rtree.AfterAdd(func(event src.Event) {
+		audit = append(audit, fmt.Sprintf("put %s %s->%s", event.Key, event.OldValue, event.NewValue))
+	})
+	rtree.BeforeDelete(func(key string, value string) bool {
+		return value != "LOCKED!"
+	})
+	rtree.AfterDelete(func(event src.Event) {
+		audit = append(audit, fmt.Sprintf("delete %s %s", event.Key, event.OldValue))
+	})
+
+	if !rtree.Add("ciao", "hello") || rtree.Add("tmpfile", "x") {
+		t.Errorf(`BeforeAdd veto error`)
+	}
+	if value, _ := rtree.Get("ciao"); value != "HELLO!" {
+		t.Errorf(`BeforeAdd transform error value=%s`, value)
+	}
+	if rtree.Contains("tmpfile") {
+		t.Errorf(`Vetoed key was stored`)
+	}
+	if value, exists := rtree.Update("tmp", func(string, bool) (string, bool) { return "y", true }); exists || value != "" {
+		t.Errorf(`Update ignored the veto value=%s exists=%v`, value, exists)
+	}
+	if old, replaced, _ := rtree.Put("ciao", "again"); old != "HELLO!" || !replaced {
+		t.Errorf(`Put error old=%s replaced=%v`, old, replaced)
+	}
+
+	rtree.Add("lock", "locked")
+	if rtree.Delete("lock") || !rtree.Contains("lock") {
+		t.Errorf(`BeforeDelete veto error`)
+	}
+	if !rtree.Delete("ciao") {
+		t.Errorf(`Delete failed key=ciao`)
+	}
+
+	expected := []string{
+		"put ciao ->HELLO!",
+		"put ciao HELLO!->AGAIN!",
+		"put lock ->LOCKED!",
+		"delete ciao AGAIN!",
+	}
+	if fmt.Sprint(audit) != fmt.Sprint(expected) {
+		t.Errorf(`Audit error expected=%v got=%v`, expected, audit)
+	}
+}
+
+func TestHooksVetoResults(t *testing.T) {
+
+	rtree := src.NewRTree()
+	rtree.Add("a", "1")
+	rtree.BeforeAdd(func(key string, value string) (string, bool) {
+		return value, false
+	})
+
+	if stored, err := rtree.Insert("b", "1"); stored || !errors.Is(err, src.ErrVetoed) || rtree.Contains("b") {
+		t.Errorf(`Insert ignored the veto stored=%v err=%v`, stored, err)
+	}
+	if stored, err := rtree.Insert("a", "2"); stored || err != nil {
+		t.Errorf(`Insert of a present key error stored=%v err=%v`, stored, err)
+	}
+	if old, replaced, err := rtree.Put("a", "2"); old != "1" || replaced || !errors.Is(err, src.ErrVetoed) {
+		t.Errorf(`Put ignored the veto old=%s replaced=%v err=%v`, old, replaced, err)
+	}
+	if old, replaced, err := rtree.Put("c", "2"); old != "" || replaced || !errors.Is(err, src.ErrVetoed) || rtree.Contains("c") {
+		t.Errorf(`Put ignored the veto of a new key old=%s replaced=%v err=%v`, old, replaced, err)
+	}
+	if actual, loaded, err := rtree.LoadOrStore("e", "5"); actual != "" || loaded || !errors.Is(err, src.ErrVetoed) || rtree.Contains("e") {
+		t.Errorf(`LoadOrStore ignored the veto actual=%s loaded=%v err=%v`, actual, loaded, err)
+	}
+	if actual, loaded, err := rtree.LoadOrStore("a", "5"); actual != "1" || !loaded || err != nil {
+		t.Errorf(`LoadOrStore of a present key error actual=%s loaded=%v err=%v`, actual, loaded, err)
+	}
+	if rtree.CompareAndSwap("a", "1", "2") {
+		t.Errorf(`CompareAndSwap ignored the veto`)
+	}
+	if value, inserted := rtree.GetOrInsert("d", func() string { return "4" }); value != "" || inserted {
+		t.Errorf(`GetOrInsert ignored the veto value=%s inserted=%v`, value, inserted)
+	}
+	if value, _ := rtree.Get("a"); value != "1" {
+		t.Errorf(`Vetoed write changed value=%s`, value)
+	}
+}
+
+func TestHooksBatch(t *testing.T) {
+
+	rtree := src.NewRTree()
+	rtree.BeforeAdd(func(key string, value string) (string, bool) {
+		return value, !strings.HasPrefix(key, "tmp")
+	})
+	rtree.BeforeDelete(func(key string, value string) bool {
+		return key != "keep"
+	})
+
+	results := rtree.AddBatch([]src.Entry{
+		{Key: "tmpa", Value: "1"},
+		{Key: "tmpb", Value: "2"},
+		{Key: "keep", Value: "3"},
+		{Key: "kept", Value: "4"},
+	})
+	if fmt.Sprint(results) != "[false false true true]" {
+		t.Errorf(`AddBatch results error got=%v`, results)
+	}
+	if err := rtree.Validate(); err != nil {
+		t.Errorf(`Vetoed AddBatch left an invalid tree: %v`, err)
+	}
+
+	results = rtree.DeleteBatch([]string{"keep", "kept"})
+	if fmt.Sprint(results) != "[false true]" || !rtree.Contains("keep") {
+		t.Errorf(`DeleteBatch results error got=%v`, results)
+	}
+}
+
+// TestHooksSecondaryIndex keeps a value to key index in sync with the tree
+func TestHooksSecondaryIndex(t *testing.T) {
+
+	rtree := src.NewRTree()
+	byValue := map[string]string{}
+	rtree.AfterAdd(func(event src.Event) {
+		if event.Existed {
+			delete(byValue, event.OldValue)
+		}
+		byValue[event.NewValue] = event.Key
+	})
+	rtree.AfterDelete(func(event src.Event) {
+		delete(byValue, event.OldValue)
+	})
+
+	rtree.Add("ciao", "1")
+	rtree.Add("help", "2")
+	rtree.Add("ciao", "3")
+	rtree.Delete("help")
+	if fmt.Sprint(byValue) != "map[3:ciao]" {
+		t.Errorf(`Secondary index error got=%v`, byValue)
+	}
+}
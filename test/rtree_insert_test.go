@@ -242,11 +242,11 @@ func analyzeTreeStructure(node *src.Node, depth int) TreeStats {
 		stats.TerminalNodes = 1
 	}
 
-	if len(node.Children) == 0 {
+	if len(node.Children()) == 0 {
 		stats.LeafNodes = 1
 	} else {
 		stats.InternalNodes = 1
-		for _, child := range node.Children {
+		for _, child := range node.Children() {
 			childStats := analyzeTreeStructure(child, depth+1)
 			stats.TotalNodes += childStats.TotalNodes
 			stats.LeafNodes += childStats.LeafNodes
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"net/netip"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestIPTableLookupIPv4(t *testing.T) {
+
+	table := src.NewIPTable()
+
+	routes := map[string]string{
+		"0.0.0.0/0":     "default",
+		"10.0.0.0/8":    "private",
+		"10.1.0.0/16":   "office",
+		"10.1.2.0/24":   "lab",
+		"10.1.2.128/25": "lab-upper",
+	}
+	for p, v := range routes {
+		if !table.Add(netip.MustParsePrefix(p), v) {
+			t.Errorf(`Fail to add prefix %s`, p)
+		}
+	}
+
+	lookups := map[string]string{
+		"10.1.2.200": "10.1.2.128/25",
+		"10.1.2.3":   "10.1.2.0/24",
+		"10.1.9.9":   "10.1.0.0/16",
+		"10.200.0.1": "10.0.0.0/8",
+		"8.8.8.8":    "0.0.0.0/0",
+	}
+	for a, want := range lookups {
+		prefix, value, found := table.Lookup(netip.MustParseAddr(a))
+		if !found || prefix.String() != want || value != routes[want] {
+			t.Errorf(`Lookup %s error prefix=%s value=%s found=%v`, a, prefix, value, found)
+		}
+	}
+}
+
+func TestIPTableLookupIPv6(t *testing.T) {
+
+	table := src.NewIPTable()
+
+	table.Add(netip.MustParsePrefix("2001:db8::/32"), "doc")
+	table.Add(netip.MustParsePrefix("2001:db8:abcd::/48"), "site")
+	table.Add(netip.MustParsePrefix("10.0.0.0/8"), "private")
+
+	prefix, value, found := table.Lookup(netip.MustParseAddr("2001:db8:abcd:1::1"))
+	if !found || prefix.String() != "2001:db8:abcd::/48" || value != "site" {
+		t.Errorf(`Lookup error prefix=%s value=%s found=%v`, prefix, value, found)
+	}
+
+	prefix, _, found = table.Lookup(netip.MustParseAddr("2001:db8:ffff::1"))
+	if !found || prefix.String() != "2001:db8::/32" {
+		t.Errorf(`Lookup error prefix=%s found=%v`, prefix, found)
+	}
+
+	_, _, found = table.Lookup(netip.MustParseAddr("2001:db9::1"))
+	if found {
+		t.Errorf(`Lookup found unexpected prefix`)
+	}
+
+	prefix, _, found = table.Lookup(netip.MustParseAddr("::ffff:10.2.3.4"))
+	if !found || prefix.String() != "10.0.0.0/8" {
+		t.Errorf(`Lookup mapped error prefix=%s found=%v`, prefix, found)
+	}
+}
+
+func TestIPTableDeleteAndWalk(t *testing.T) {
+
+	table := src.NewIPTable()
+
+	table.Add(netip.MustParsePrefix("192.168.0.0/16"), "lan")
+	table.Add(netip.MustParsePrefix("192.168.1.77/24"), "wifi")
+	table.Add(netip.MustParsePrefix("fd00::/8"), "ula")
+
+	value, found := table.Get(netip.MustParsePrefix("192.168.1.0/24"))
+	if !found || value != "wifi" {
+		t.Errorf(`Get error value=%s found=%v`, value, found)
+	}
+
+	if !table.Delete(netip.MustParsePrefix("192.168.1.0/24")) {
+		t.Errorf(`Fail to delete prefix 192.168.1.0/24`)
+	}
+	if table.Delete(netip.MustParsePrefix("192.168.1.0/24")) {
+		t.Errorf(`Deleted missing prefix 192.168.1.0/24`)
+	}
+
+	prefix, _, _ := table.Lookup(netip.MustParseAddr("192.168.1.5"))
+	if prefix.String() != "192.168.0.0/16" {
+		t.Errorf(`Lookup after delete error prefix=%s`, prefix)
+	}
+
+	walked := []string{}
+	table.Walk(func(prefix netip.Prefix, value string) bool {
+		walked = append(walked, prefix.String())
+		return true
+	})
+	if len(walked) != 2 || walked[0] != "192.168.0.0/16" || walked[1] != "fd00::/8" || table.Len() != 2 {
+		t.Errorf(`Walk error walked=%v len=%d`, walked, table.Len())
+	}
+}
+
+func TestIPTableMappedPrefix(t *testing.T) {
+
+	table := src.NewIPTable()
+
+	if !table.Add(netip.MustParsePrefix("::ffff:10.0.0.0/104"), "mapped") {
+		t.Fatalf(`Fail to add prefix ::ffff:10.0.0.0/104`)
+	}
+	if table.Add(netip.MustParsePrefix("::ffff:0.0.0.0/95"), "wide") {
+		t.Errorf(`Added a mapped prefix shorter than 96 bits`)
+	}
+
+	if value, found := table.Get(netip.MustParsePrefix("10.0.0.0/8")); !found || value != "mapped" {
+		t.Errorf(`Get unmapped error value=%s found=%v`, value, found)
+	}
+	if value, found := table.Get(netip.MustParsePrefix("::ffff:10.0.0.0/104")); !found || value != "mapped" {
+		t.Errorf(`Get mapped error value=%s found=%v`, value, found)
+	}
+	for _, a := range []string{"::ffff:10.1.2.3", "10.1.2.3"} {
+		prefix, value, found := table.Lookup(netip.MustParseAddr(a))
+		if !found || prefix.String() != "10.0.0.0/8" || value != "mapped" {
+			t.Errorf(`Lookup %s error prefix=%s value=%s found=%v`, a, prefix, value, found)
+		}
+	}
+
+	if !table.Delete(netip.MustParsePrefix("::ffff:10.0.0.0/104")) || table.Len() != 0 {
+		t.Errorf(`Delete mapped error len=%d`, table.Len())
+	}
+}
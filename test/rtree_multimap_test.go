@@ -0,0 +1,68 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestMultiRTreeAppendAndValues(t *testing.T) {
+
+	tree := src.NewMultiRTree()
+
+	tree.Append("customer:42", "order-1")
+	tree.Append("customer:42", "order-2")
+	tree.Append("customer:7", "order-3")
+	if tree.Append("customer:42", "order-1") {
+		t.Errorf(`Duplicate value added`)
+	}
+	if tree.Append("", "order-4") || tree.Append(src.ROOT, "order-4") {
+		t.Errorf(`Invalid key added`)
+	}
+
+	values := tree.Values("customer:42")
+	if strings.Join(values, ",") != "order-1,order-2" {
+		t.Errorf(`Values error values=%v`, values)
+	}
+	if len(tree.Values("customer:1")) != 0 {
+		t.Errorf(`Values of missing key error`)
+	}
+
+	values = tree.PrefixValues("customer:")
+	if strings.Join(values, ",") != "order-1,order-2,order-3" {
+		t.Errorf(`PrefixValues error values=%v`, values)
+	}
+}
+
+func TestMultiRTreeRemoveValue(t *testing.T) {
+
+	tree := src.NewMultiRTree()
+
+	tree.Append("tag:go", "a")
+	tree.Append("tag:go", "b")
+	tree.Append("tag:golang", "c")
+
+	if !tree.RemoveValue("tag:go", "a") || tree.RemoveValue("tag:go", "a") {
+		t.Errorf(`RemoveValue error`)
+	}
+	if tree.Len() != 2 {
+		t.Errorf(`Len error len=%d`, tree.Len())
+	}
+
+	if !tree.RemoveValue("tag:go", "b") {
+		t.Errorf(`RemoveValue error`)
+	}
+	if tree.Len() != 1 {
+		t.Errorf(`Key not removed with its last value len=%d`, tree.Len())
+	}
+
+	keys := []string{}
+	tree.WalkPrefix("tag:", func(key string, values []string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if strings.Join(keys, ",") != "tag:golang" {
+		t.Errorf(`WalkPrefix error keys=%v`, keys)
+	}
+}
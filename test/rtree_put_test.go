@@ -0,0 +1,71 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestPut(t *testing.T) {
+
+	rtree := src.NewRTree()
+
+	old, replaced, err := rtree.Put("ciao", "v1")
+	if err != nil || replaced || old != "" {
+		t.Errorf(`Put new key error old=%s replaced=%v err=%v`, old, replaced, err)
+	}
+
+	old, replaced, err = rtree.Put("ciao", "v2")
+	if err != nil || !replaced || old != "v1" || rtree.Search("ciao").Value != "v2" {
+		t.Errorf(`Put overwrite error old=%s replaced=%v err=%v`, old, replaced, err)
+	}
+
+	// "cia" is a split point of "ciao" but not a stored key
+	rtree.Put("ciauz", "v3")
+	old, replaced, _ = rtree.Put("cia", "v4")
+	if replaced || old != "" {
+		t.Errorf(`Put on intermediate node error old=%s replaced=%v`, old, replaced)
+	}
+}
+
+func TestInsert(t *testing.T) {
+
+	rtree := src.NewRTree()
+
+	inserted, err := rtree.Insert("help", "v1")
+	if err != nil || !inserted {
+		t.Errorf(`Insert new key error inserted=%v err=%v`, inserted, err)
+	}
+
+	inserted, err = rtree.Insert("help", "v2")
+	if err != nil || inserted || rtree.Search("help").Value != "v1" {
+		t.Errorf(`Insert overwrote existing key inserted=%v err=%v`, inserted, err)
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+
+	rtree := src.NewRTree()
+
+	if _, _, err := rtree.Put("", "v"); !errors.Is(err, src.ErrEmptyKey) {
+		t.Errorf(`Put empty key error err=%v`, err)
+	}
+	if _, _, err := rtree.Put(src.ROOT, "v"); !errors.Is(err, src.ErrReservedKey) {
+		t.Errorf(`Put reserved key error err=%v`, err)
+	}
+	if _, err := rtree.Insert("", "v"); !errors.Is(err, src.ErrEmptyKey) {
+		t.Errorf(`Insert empty key error err=%v`, err)
+	}
+	if rtree.Add("", "v") || rtree.Add(src.ROOT, "v") {
+		t.Errorf(`Add accepted an invalid key`)
+	}
+	if len(rtree.Root.Children()) != 0 {
+		t.Errorf(`Invalid key changed the tree len=%d`, len(rtree.Root.Children()))
+	}
+
+	// ROOT is reserved only as a whole key
+	if !rtree.Add("ROOTS", "v") || !rtree.Add("xROOT", "v") {
+		t.Errorf(`Add rejected a valid key`)
+	}
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"rtree/src"
+)
+
+func collectWalk(walk func(fn func(key string, value string) bool)) []string {
+	found := []string{}
+	walk(func(key string, value string) bool {
+		found = append(found, key)
+		return true
+	})
+	sort.Strings(found)
+	return found
+}
+
+func TestReverseLabelsStoredKey(t *testing.T) {
+
+	rtree := src.NewReverseRTree(src.ReverseLabels, "")
+
+	stored := rtree.StoredKey("api.example.com")
+	if stored != "com.example.api" {
+		t.Errorf(`StoredKey error %s`, stored)
+	}
+	if rtree.NaturalKey(stored) != "api.example.com" {
+		t.Errorf(`NaturalKey error %s`, rtree.NaturalKey(stored))
+	}
+
+	rtree.Add("api.example.com", "10.0.0.1")
+	if rtree.Tree().Search("com.example.api") == nil {
+		t.Errorf(`Not Found expected stored key com.example.api`)
+	}
+	node := rtree.Search("api.example.com")
+	if node == nil || node.Value != "10.0.0.1" {
+		t.Errorf(`Not Found expected key api.example.com`)
+	}
+}
+
+func TestReverseLabelsWildcard(t *testing.T) {
+
+	rtree := src.NewReverseRTree(src.ReverseLabels, ".")
+
+	keys := []string{"example.com", "api.example.com", "www.example.com", "v1.api.example.com", "myexample.com", "example.org"}
+	for _, k := range keys {
+		rtree.Add(k, k)
+	}
+
+	found := collectWalk(func(fn func(key string, value string) bool) {
+		rtree.WalkWildcard("*.example.com", fn)
+	})
+	if strings.Join(found, ",") != "api.example.com,v1.api.example.com,www.example.com" {
+		t.Errorf(`WalkWildcard error found=%v`, found)
+	}
+
+	found = collectWalk(func(fn func(key string, value string) bool) {
+		rtree.WalkSuffix("example.com", fn)
+	})
+	if strings.Join(found, ",") != "api.example.com,example.com,v1.api.example.com,www.example.com" {
+		t.Errorf(`WalkSuffix error found=%v`, found)
+	}
+
+	found = collectWalk(func(fn func(key string, value string) bool) {
+		rtree.WalkWildcard("example.org", fn)
+	})
+	if strings.Join(found, ",") != "example.org" {
+		t.Errorf(`WalkWildcard exact error found=%v`, found)
+	}
+
+	if !rtree.Delete("api.example.com") || rtree.Search("api.example.com") != nil {
+		t.Errorf(`Fail to delete key api.example.com`)
+	}
+}
+
+func TestReverseBytesSuffix(t *testing.T) {
+
+	rtree := src.NewReverseRTree(src.ReverseBytes, "")
+
+	keys := []string{"report.pdf", "invoice.pdf", "invoice.txt", "pdf"}
+	for _, k := range keys {
+		rtree.Add(k, k)
+	}
+
+	if rtree.StoredKey("invoice.pdf") != "fdp.eciovni" {
+		t.Errorf(`StoredKey error %s`, rtree.StoredKey("invoice.pdf"))
+	}
+
+	found := collectWalk(func(fn func(key string, value string) bool) {
+		rtree.WalkSuffix(".pdf", fn)
+	})
+	if strings.Join(found, ",") != "invoice.pdf,report.pdf" {
+		t.Errorf(`WalkSuffix error found=%v`, found)
+	}
+
+	found = collectWalk(func(fn func(key string, value string) bool) {
+		rtree.WalkWildcard("*pdf", fn)
+	})
+	if strings.Join(found, ",") != "invoice.pdf,report.pdf" {
+		t.Errorf(`WalkWildcard error found=%v`, found)
+	}
+}
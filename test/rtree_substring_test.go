@@ -0,0 +1,99 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestSubstringIndexContains(t *testing.T) {
+
+	index := src.NewSubstringIndex()
+
+	keys := []string{"invoice-2024", "proforma-invoice", "receipt", "voice"}
+	for _, k := range keys {
+		if !index.Add(k) {
+			t.Errorf(`Fail to add key %s`, k)
+		}
+	}
+
+	found := index.Contains("voice")
+	if strings.Join(found, ",") != "invoice-2024,proforma-invoice,voice" {
+		t.Errorf(`Contains error found=%v`, found)
+	}
+
+	found = index.Contains("invoice")
+	if strings.Join(found, ",") != "invoice-2024,proforma-invoice" {
+		t.Errorf(`Contains error found=%v`, found)
+	}
+
+	found = index.Contains("missing")
+	if len(found) != 0 {
+		t.Errorf(`Contains error found=%v`, found)
+	}
+
+	if len(index.Contains("")) != len(keys) {
+		t.Errorf(`Contains empty error len=%d`, len(index.Contains("")))
+	}
+}
+
+func TestSubstringIndexHasSuffix(t *testing.T) {
+
+	index := src.NewSubstringIndex()
+
+	keys := []string{"invoice", "voice", "choice", "ROOT", "xROOT"}
+	for _, k := range keys {
+		index.Add(k)
+	}
+
+	found := index.HasSuffix("oice")
+	if strings.Join(found, ",") != "choice,invoice,voice" {
+		t.Errorf(`HasSuffix error found=%v`, found)
+	}
+
+	found = index.HasSuffix("voi")
+	if len(found) != 0 {
+		t.Errorf(`HasSuffix error found=%v`, found)
+	}
+
+	found = index.HasSuffix("ROOT")
+	if strings.Join(found, ",") != "ROOT,xROOT" {
+		t.Errorf(`HasSuffix error found=%v`, found)
+	}
+}
+
+func TestSubstringIndexDelete(t *testing.T) {
+
+	index := src.NewSubstringIndex()
+
+	keys := []string{"abab", "bab", "ab"}
+	for _, k := range keys {
+		index.Add(k)
+	}
+
+	if index.Add("ab") {
+		t.Errorf(`Duplicate key ab added`)
+	}
+
+	if !index.Delete("abab") {
+		t.Errorf(`Fail to delete key abab`)
+	}
+	if index.Delete("abab") {
+		t.Errorf(`Deleted missing key abab`)
+	}
+
+	found := index.Contains("ba")
+	if strings.Join(found, ",") != "bab" {
+		t.Errorf(`Contains error found=%v`, found)
+	}
+
+	found = index.HasSuffix("ab")
+	if strings.Join(found, ",") != "ab,bab" {
+		t.Errorf(`HasSuffix error found=%v`, found)
+	}
+
+	if index.Len() != 2 {
+		t.Errorf(`Len error len=%d`, index.Len())
+	}
+}
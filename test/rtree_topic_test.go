@@ -0,0 +1,136 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestTopicTreeMatch(t *testing.T) {
+
+	tree := src.NewTopicTree()
+
+	subscriptions := map[string]string{
+		"exact":    "sensors/kitchen/temp",
+		"single":   "sensors/+/temp",
+		"multi":    "sensors/#",
+		"all":      "#",
+		"humidity": "sensors/+/humidity",
+		"deep":     "sensors/+/+/battery",
+		"system":   "$SYS/#",
+	}
+	for subscriber, filter := range subscriptions {
+		if !tree.Subscribe(filter, subscriber) {
+			t.Errorf(`Fail to subscribe %s to %s`, subscriber, filter)
+		}
+	}
+
+	expected := map[string]string{
+		"sensors/kitchen/temp":      "all,exact,multi,single",
+		"sensors/garage/temp":       "all,multi,single",
+		"sensors/garage/humidity":   "all,humidity,multi",
+		"sensors":                   "all,multi",
+		"sensors/a/b/battery":       "all,deep,multi",
+		"lights/kitchen":            "all",
+		"$SYS/broker/uptime":        "system",
+		"sensors/kitchen/temp/unit": "all,multi",
+	}
+	for topic, want := range expected {
+		found := tree.Match(topic)
+		if strings.Join(found, ",") != want {
+			t.Errorf(`Match %s error found=%v`, topic, found)
+		}
+	}
+}
+
+func TestTopicTreeSubscribersAndUnsubscribe(t *testing.T) {
+
+	tree := src.NewTopicTree()
+
+	tree.Subscribe("home/+/light", "a")
+	tree.Subscribe("home/+/light", "b")
+	if tree.Subscribe("home/+/light", "a") {
+		t.Errorf(`Duplicate subscription added`)
+	}
+
+	found := tree.Subscribers("home/+/light")
+	if strings.Join(found, ",") != "a,b" {
+		t.Errorf(`Subscribers error found=%v`, found)
+	}
+
+	if !tree.Unsubscribe("home/+/light", "a") || tree.Unsubscribe("home/+/light", "a") {
+		t.Errorf(`Unsubscribe error`)
+	}
+	found = tree.Match("home/hall/light")
+	if strings.Join(found, ",") != "b" {
+		t.Errorf(`Match error found=%v`, found)
+	}
+
+	tree.Unsubscribe("home/+/light", "b")
+	if len(tree.Root.Children()) != 0 {
+		t.Errorf(`Unsubscribe did not prune levels len=%d`, len(tree.Root.Children()))
+	}
+}
+
+func TestTopicTreeInvalidFilters(t *testing.T) {
+
+	tree := src.NewTopicTree()
+
+	filters := []string{"", "sensors/#/temp", "sensors/te+", "sensors#", "a/b#"}
+	for _, filter := range filters {
+		if tree.Subscribe(filter, "x") {
+			t.Errorf(`Invalid filter %s accepted`, filter)
+		}
+	}
+
+	tree.Subscribe("#", "x")
+	if len(tree.Match("sensors/+")) != 0 {
+		t.Errorf(`Topic with wildcard matched`)
+	}
+}
+
+func TestTopicTreeSharedPrefixes(t *testing.T) {
+
+	tree := src.NewTopicTree()
+
+	subscriptions := map[string]string{
+		"home":   "home/+/light",
+		"hall":   "hall/#",
+		"ho":     "ho/+",
+		"root":   "ROOT",
+		"empty":  "a/+/b",
+		"parent": "home/#",
+	}
+	for subscriber, filter := range subscriptions {
+		if !tree.Subscribe(filter, subscriber) {
+			t.Errorf(`Fail to subscribe %s to %s`, subscriber, filter)
+		}
+	}
+
+	expected := map[string]string{
+		"home/kitchen/light": "home,parent",
+		"home":               "parent",
+		"home/":              "parent",
+		"hall":               "hall",
+		"ho/x":               "ho",
+		"ho":                 "",
+		"hom/x":              "",
+		"ROOT":               "root",
+		"a//b":               "empty",
+		"a/b":                "",
+	}
+	for topic, want := range expected {
+		found := tree.Match(topic)
+		if strings.Join(found, ",") != want {
+			t.Errorf(`Match %s error found=%v`, topic, found)
+		}
+	}
+
+	for subscriber, filter := range subscriptions {
+		tree.Unsubscribe(filter, subscriber)
+	}
+	if len(tree.Root.Children()) != 0 {
+		t.Errorf(`Unsubscribe did not prune levels len=%d`, len(tree.Root.Children()))
+	}
+}
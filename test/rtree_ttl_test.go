@@ -0,0 +1,161 @@
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"rtree/src"
+)
+
+func TestAddWithTTL(t *testing.T) {
+
+	rtree := src.NewRTree()
+	rtree.Add("ciao", "permanent")
+	if rtree.AddWithTTL("session", "x", 0) {
+		t.Errorf(`AddWithTTL accepted a zero ttl`)
+	}
+	rtree.AddWithTTL("ciaone", "short", 30*time.Millisecond)
+	rtree.AddWithTTL("ciauz", "short", 30*time.Millisecond)
+	rtree.AddWithTTL("help", "long", time.Hour)
+	rtree.AddWithTTL("test", "made permanent", 30*time.Millisecond)
+	rtree.Add("test", "made permanent")
+
+	if ttl, ok := rtree.TTL("ciaone"); !ok || ttl <= 0 || ttl > 30*time.Millisecond {
+		t.Errorf(`TTL error ttl=%v ok=%v`, ttl, ok)
+	}
+	if ttl, ok := rtree.TTL("ciao"); !ok || ttl != src.NoTTL {
+		t.Errorf(`TTL on permanent key error ttl=%v ok=%v`, ttl, ok)
+	}
+	if _, ok := rtree.TTL("missing"); ok {
+		t.Errorf(`TTL found a missing key`)
+	}
+	if value, _ := rtree.Get("ciaone"); value != "short" {
+		t.Errorf(`Not Found expected key ciaone before expiry`)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if rtree.Contains("ciaone") || rtree.Search("ciaone") != nil {
+		t.Errorf(`Found expired key ciaone`)
+	}
+	if _, ok := rtree.TTL("ciauz"); ok {
+		t.Errorf(`TTL found expired key ciauz`)
+	}
+	if rtree.Delete("ciauz") {
+		t.Errorf(`Delete removed expired key ciauz`)
+	}
+	found := []string{}
+	rtree.WalkPrefix("", func(key string, value string) bool {
+		found = append(found, key)
+		return true
+	})
+	if fmt.Sprint(found) != "[ciao help test]" {
+		t.Errorf(`WalkPrefix error got=%v`, found)
+	}
+	if err := rtree.Validate(); err != nil {
+		t.Errorf(`Expiry left an invalid tree: %v`, err)
+	}
+}
+
+func TestTTLJanitor(t *testing.T) {
+
+	rtree := src.NewRTree()
+	rtree.Add("keep", "value")
+
+	var mu sync.Mutex
+	expired := []string{}
+	rtree.AfterDelete(func(event src.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if event.Expired {
+			expired = append(expired, event.Key)
+		}
+	})
+	events, cancel := rtree.Watch("session/")
+	defer cancel()
+
+	for i := 0; i < 100; i++ {
+		rtree.AddWithTTL(fmt.Sprintf("session/%03d", i), "token", 20*time.Millisecond)
+	}
+	for i := 0; i < 100; i++ {
+		<-events
+	}
+
+	stop := rtree.StartJanitor(5 * time.Millisecond)
+	defer stop()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		done := len(expired) == 100
+		mu.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+
+	if _, exists := rtree.Root.Child("keep"); !exists || len(rtree.Root.Children()) != 1 {
+		t.Errorf(`Janitor did not remove the expired paths children=%d`, len(rtree.Root.Children()))
+	}
+	mu.Lock()
+	if len(expired) != 100 || expired[0] != "session/000" {
+		t.Errorf(`AfterDelete received %d expiry events`, len(expired))
+	}
+	mu.Unlock()
+	if event := nextEvent(t, events); event.Type != src.EventDelete || !event.Expired {
+		t.Errorf(`Watch expiry event error got=%+v`, event)
+	}
+}
+
+func TestTTLReadOnlyViews(t *testing.T) {
+
+	rtree := src.NewRTree()
+	rtree.Add("cia", "permanent")
+	rtree.AddWithTTL("ciao", "short", 20*time.Millisecond)
+	rtree.AddWithTTL("ciaone", "short", 20*time.Millisecond)
+	rtree.AddWithTTL("help", "short", 20*time.Millisecond)
+	rtree.Add("helper", "permanent")
+
+	time.Sleep(30 * time.Millisecond)
+
+	// Nothing read the expired keys, their nodes are still in the tree
+	if _, found := rtree.Seek("ciao"); found {
+		t.Errorf(`Seek found expired subtree ciao`)
+	}
+	cursor, found := rtree.Seek("help")
+	if !found || cursor.IsEnd() || cursor.Value() != "" {
+		t.Errorf(`Seek error on expired key help with live children`)
+	}
+	cursor, _ = rtree.Seek("cia")
+	if children := cursor.Children(); len(children) != 0 {
+		t.Errorf(`Cursor.Children returned %d expired children`, len(children))
+	}
+
+	path := filepath.Join(t.TempDir(), "tree.frozen")
+	if err := src.WriteFrozenFile(rtree, path); err != nil {
+		t.Fatalf(`WriteFrozenFile error %v`, err)
+	}
+	frozen, err := src.OpenFrozen(path)
+	if err != nil {
+		t.Fatalf(`OpenFrozen error %v`, err)
+	}
+	defer frozen.Close()
+
+	for _, k := range []string{"ciao", "ciaone", "help"} {
+		if frozen.Contains(k) {
+			t.Errorf(`Frozen tree holds expired key %s`, k)
+		}
+	}
+	frozenKeys := []string{}
+	frozen.WalkPrefix("", func(key string, value string) bool {
+		frozenKeys = append(frozenKeys, key)
+		return true
+	})
+	if fmt.Sprint(frozenKeys) != "[cia helper]" {
+		t.Errorf(`Frozen WalkPrefix error got=%v`, frozenKeys)
+	}
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"rtree/src"
+)
+
+func TestUpdate(t *testing.T) {
+
+	rtree := src.NewRTree()
+	rtree.Add("ciao", "1")
+
+	value, exists := rtree.Update("ciao", func(old string, exists bool) (string, bool) {
+		if !exists || old != "1" {
+			t.Errorf(`Update received old=%s exists=%v`, old, exists)
+		}
+		return "2", true
+	})
+	if !exists || value != "2" || rtree.Search("ciao").Value != "2" {
+		t.Errorf(`Update error value=%s exists=%v`, value, exists)
+	}
+
+	value, exists = rtree.Update("ciaone", func(old string, exists bool) (string, bool) {
+		if exists {
+			t.Errorf(`Update received missing key as existing`)
+		}
+		return "skipped", false
+	})
+	if exists || value != "" || rtree.Search("ciaone") != nil {
+		t.Errorf(`Update stored a vetoed value`)
+	}
+
+	// Vetoing a split must leave the edge untouched
+	rtree.Update("ci", func(string, bool) (string, bool) {
+		return "", false
+	})
+	if _, exists := rtree.Root.Child("ciao"); !exists || rtree.Search("ciao") == nil {
+		t.Errorf(`Vetoed update changed the tree`)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+
+	rtree := src.NewRTree()
+	rtree.Add("help", "v1")
+
+	if rtree.CompareAndSwap("help", "v0", "v2") {
+		t.Errorf(`CompareAndSwap swapped a wrong old value`)
+	}
+	if !rtree.CompareAndSwap("help", "v1", "v2") || rtree.Search("help").Value != "v2" {
+		t.Errorf(`CompareAndSwap failed`)
+	}
+	if rtree.CompareAndSwap("helper", "", "v1") || rtree.Search("helper") != nil {
+		t.Errorf(`CompareAndSwap inserted a missing key`)
+	}
+}
+
+func TestLoadOrStoreAndGetOrInsert(t *testing.T) {
+
+	rtree := src.NewRTree()
+
+	actual, loaded, err := rtree.LoadOrStore("test", "first")
+	if loaded || actual != "first" || err != nil {
+		t.Errorf(`LoadOrStore error actual=%s loaded=%v err=%v`, actual, loaded, err)
+	}
+	actual, loaded, err = rtree.LoadOrStore("test", "second")
+	if !loaded || actual != "first" || err != nil {
+		t.Errorf(`LoadOrStore error actual=%s loaded=%v err=%v`, actual, loaded, err)
+	}
+	if _, _, err := rtree.LoadOrStore("", "v"); !errors.Is(err, src.ErrEmptyKey) {
+		t.Errorf(`LoadOrStore empty key error err=%v`, err)
+	}
+
+	calls := 0
+	create := func() string {
+		calls++
+		return "created"
+	}
+	actual, inserted := rtree.GetOrInsert("testing", create)
+	if !inserted || actual != "created" {
+		t.Errorf(`GetOrInsert error actual=%s inserted=%v`, actual, inserted)
+	}
+	actual, inserted = rtree.GetOrInsert("testing", create)
+	if inserted || actual != "created" || calls != 1 {
+		t.Errorf(`GetOrInsert error actual=%s inserted=%v calls=%d`, actual, inserted, calls)
+	}
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+
+	rtree := src.NewRTree()
+
+	workers := 20
+	increments := 200
+	keys := []string{"counter", "count", "counters"}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < increments; i++ {
+				for _, k := range keys {
+					rtree.Update(k, func(old string, exists bool) (string, bool) {
+						n, _ := strconv.Atoi(old)
+						return strconv.Itoa(n + 1), true
+					})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, k := range keys {
+		node := rtree.Search(k)
+		if node == nil || node.Value != strconv.Itoa(workers*increments) {
+			t.Errorf(`Concurrent update lost writes on key %s`, k)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"rtree/src"
+)
+
+func validateTree() *src.RTree {
+	rtree := src.NewRTree()
+	for _, k := range []string{"ciao", "ciaone", "ciauz", "help", "helper", "cia", "test"} {
+		rtree.Add(k, fmt.Sprintf("val of %s", k))
+	}
+	return rtree
+}
+
+func TestValidate(t *testing.T) {
+
+	rtree := validateTree()
+	if err := rtree.Validate(); err != nil {
+		t.Fatalf(`Validate error on a valid tree: %v`, err)
+	}
+
+	// Deleting ciao leaves cia -> o with a single child until Compact
+	rtree.Delete("ciao")
+	if err := rtree.Validate(); err != nil {
+		t.Errorf(`Validate error after Delete: %v`, err)
+	}
+	rtree.Compact()
+	if err := rtree.Validate(); err != nil {
+		t.Errorf(`Validate error after Compact: %v`, err)
+	}
+}
+
+// childAt follows edges from node
+func childAt(node *src.Node, edges ...string) *src.Node {
+	for _, edge := range edges {
+		node, _ = node.Child(edge)
+	}
+	return node
+}
+
+func TestValidateReportsPath(t *testing.T) {
+
+	tests := map[string]struct {
+		corrupt func(rtree *src.RTree)
+		path    []string
+		reason  string
+	}{
+		"index byte": {
+			corrupt: func(rtree *src.RTree) {
+				childAt(rtree.Root, "help", "er").Key = "xr"
+			},
+			path:   []string{"help", "xr"},
+			reason: "indexed under byte 'e'",
+		},
+		"empty edge": {
+			corrupt: func(rtree *src.RTree) {
+				childAt(rtree.Root, "cia", "o", "ne").Key = ""
+			},
+			path:   []string{"cia", "o", ""},
+			reason: "empty edge",
+		},
+		"single child": {
+			corrupt: func(rtree *src.RTree) {
+				childAt(rtree.Root, "help").IsEnd = false
+			},
+			path:   []string{"help"},
+			reason: "non-terminal node with 1 children",
+		},
+		"cycle": {
+			corrupt: func(rtree *src.RTree) {
+				loop := src.NewNode("x", "")
+				rtree.AddNodesToChildren(loop, childAt(rtree.Root, "test"))
+				rtree.AddNodesToChildren(childAt(rtree.Root, "test"), loop)
+			},
+			path:   []string{"test", "x", "test"},
+			reason: "reachable twice",
+		},
+	}
+
+	for name, test := range tests {
+		rtree := validateTree()
+		test.corrupt(rtree)
+		err := rtree.Validate()
+		var validationErr *src.ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf(`%s: Validate returned %v`, name, err)
+			continue
+		}
+		if fmt.Sprint(validationErr.Path) != fmt.Sprint(test.path) || !strings.Contains(validationErr.Reason, test.reason) {
+			t.Errorf(`%s: Validate returned path=%q reason=%s`, name, validationErr.Path, validationErr.Reason)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"rtree/src"
+)
+
+func nextEvent(t *testing.T, events <-chan src.Event) src.Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf(`No event received`)
+		return src.Event{}
+	}
+}
+
+func expectNoEvent(t *testing.T, events <-chan src.Event) {
+	t.Helper()
+	select {
+	case event := <-events:
+		t.Errorf(`Unexpected event %+v`, event)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestWatch(t *testing.T) {
+
+	rtree := src.NewRTree()
+	rtree.Add("ciao", "old")
+
+	events, cancel := rtree.Watch("cia")
+	defer cancel()
+	all, cancelAll := rtree.Watch("")
+	defer cancelAll()
+
+	rtree.Add("ciao", "new")
+	rtree.Add("help", "val of help")
+	rtree.Put("ciauz", "val of ciauz")
+	rtree.Update("ciao", func(old string, exists bool) (string, bool) {
+		return "", false
+	})
+	rtree.Delete("ciao")
+	rtree.Delete("missing")
+
+	expected := []src.Event{
+		{Type: src.EventPut, Key: "ciao", OldValue: "old", NewValue: "new", Existed: true},
+		{Type: src.EventPut, Key: "ciauz", NewValue: "val of ciauz"},
+		{Type: src.EventDelete, Key: "ciao", OldValue: "new", Existed: true},
+	}
+	for _, want := range expected {
+		if event := nextEvent(t, events); event != want {
+			t.Errorf(`Watch event error expected=%+v got=%+v`, want, event)
+		}
+	}
+	expectNoEvent(t, events)
+
+	keys := []string{}
+	for range 4 {
+		keys = append(keys, nextEvent(t, all).Key)
+	}
+	if fmt.Sprint(keys) != "[ciao help ciauz ciao]" {
+		t.Errorf(`Watch on empty prefix error got=%v`, keys)
+	}
+}
+
+func TestWatchBatch(t *testing.T) {
+
+	rtree := src.NewRTree()
+	rtree.Add("help", "old")
+	events, cancel := rtree.Watch("help")
+	defer cancel()
+
+	rtree.AddBatch([]src.Entry{{Key: "helper", Value: "a"}, {Key: "ciao", Value: "b"}, {Key: "help", Value: "c"}})
+	rtree.DeleteBatch([]string{"helper", "ciao", "missing"})
+
+	expected := []src.Event{
+		{Type: src.EventPut, Key: "help", OldValue: "old", NewValue: "c", Existed: true},
+		{Type: src.EventPut, Key: "helper", NewValue: "a"},
+		{Type: src.EventDelete, Key: "helper", OldValue: "a", Existed: true},
+	}
+	for _, want := range expected {
+		if event := nextEvent(t, events); event != want {
+			t.Errorf(`Watch event error expected=%+v got=%+v`, want, event)
+		}
+	}
+	expectNoEvent(t, events)
+}
+
+func TestWatchCancel(t *testing.T) {
+
+	rtree := src.NewRTree()
+	events, cancel := rtree.Watch("key")
+
+	// Nobody reads yet, writers must not block on the watcher
+	for i := 0; i < 10000; i++ {
+		rtree.Add(fmt.Sprintf("key%d", i), "value")
+	}
+	if event := nextEvent(t, events); event.Key != "key0" {
+		t.Errorf(`First event error got=%+v`, event)
+	}
+
+	cancel()
+	cancel()
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, open := <-events:
+			if !open {
+				rtree.Add("key", "after cancel")
+				return
+			}
+		case <-deadline:
+			t.Fatalf(`Channel not closed after cancel`)
+		}
+	}
+}